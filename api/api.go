@@ -1,7 +1,10 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path"
@@ -9,6 +12,8 @@ import (
 	"github.com/Erexo/Ventana/api/controller"
 	_ "github.com/Erexo/Ventana/docs"
 	"github.com/Erexo/Ventana/infrastructure/config"
+	"github.com/Erexo/Ventana/infrastructure/gpio"
+	"github.com/Erexo/Ventana/infrastructure/inventory"
 	"github.com/Erexo/Ventana/infrastructure/light"
 	"github.com/Erexo/Ventana/infrastructure/sunblind"
 	"github.com/Erexo/Ventana/infrastructure/thermal"
@@ -34,10 +39,15 @@ type UnauthorizedController interface {
 	UnauthorizedRoute(r chi.Router)
 }
 
-func Run(us *user.Service, ts *thermal.Service, ss *sunblind.Service, ls *light.Service) error {
+// Server serves the API on the configured address, it is disabled when none is set.
+type Server struct {
+	server *http.Server
+}
+
+func CreateServer(us *user.Service, ts *thermal.Service, ss *sunblind.Service, ls *light.Service, gs *gpio.Service, is *inventory.Service) *Server {
 	config := config.GetConfig()
 	if !config.ApiAddr.Valid {
-		return nil
+		return &Server{}
 	}
 
 	r := chi.NewRouter()
@@ -48,6 +58,8 @@ func Run(us *user.Service, ts *thermal.Service, ss *sunblind.Service, ls *light.
 	registerController(r, us, token, thermal.CreateController(ts))
 	registerController(r, us, token, sunblind.CreateController(ss))
 	registerController(r, us, token, light.CreateController(ls))
+	registerController(r, us, token, gpio.CreateController(gs))
+	registerController(r, us, token, inventory.CreateController(is))
 
 	if config.UseWebDir {
 		if _, err := os.Stat(webDir); os.IsNotExist(err) {
@@ -63,8 +75,43 @@ func Run(us *user.Service, ts *thermal.Service, ss *sunblind.Service, ls *light.
 	if config.UseSwagger {
 		r.Mount("/swagger", httpSwagger.WrapHandler)
 	}
-	log.Printf("Initializing API '%s'\n", config.ApiAddr.String)
-	return http.ListenAndServe(config.ApiAddr.String, r)
+	server := &http.Server{Addr: config.ApiAddr.String, Handler: r}
+	// the streaming requests only end with their context, which is cancelled once the shutdown begins
+	ctx, cancel := context.WithCancel(context.Background())
+	server.BaseContext = func(net.Listener) context.Context {
+		return ctx
+	}
+	server.RegisterOnShutdown(cancel)
+	return &Server{
+		server: server,
+	}
+}
+
+// Start listens on the address right away, so a taken one is reported, and serves the requests in the background.
+func (s *Server) Start(ctx context.Context) error {
+	if s.server == nil {
+		return nil
+	}
+	log.Printf("Initializing API '%s'\n", s.server.Addr)
+	var lc net.ListenConfig
+	l, err := lc.Listen(ctx, "tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := s.server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+			log.Println("Api error:", err)
+		}
+	}()
+	return nil
+}
+
+// Stop refuses new connections and waits for the requests in progress, until the context is done.
+func (s *Server) Stop(ctx context.Context) error {
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Shutdown(ctx)
 }
 
 func addCors(r *chi.Mux) {
@@ -46,6 +46,27 @@ func ReadClaims(w http.ResponseWriter, r *http.Request) (Claims, bool) {
 	}, true
 }
 
+// RequireRole rejects the requests of users below the given role.
+func RequireRole(role domain.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ReadClaims(w, r)
+			if !ok {
+				return
+			}
+			if claims.Role < role {
+				Forbid(w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func Unauthorize(w http.ResponseWriter) {
 	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 }
+
+func Forbid(w http.ResponseWriter) {
+	http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+}
@@ -0,0 +1,9 @@
+package domain
+
+import "time"
+
+type Milliseconds int64
+
+func (m Milliseconds) Duration() time.Duration {
+	return time.Duration(m) * time.Millisecond
+}
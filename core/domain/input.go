@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"errors"
+
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+// InputSettings describe how the wall switch of a device is wired and interpreted.
+// Without any action the switch drives the output directly, each change flipping it.
+type InputSettings struct {
+	InputPolarity     enum.Polarity `json:"inputpolarity" db:"inputpolarity"`
+	InputPull         enum.Pull     `json:"inputpull" db:"inputpull"`
+	Debounce          Milliseconds  `json:"debounce" db:"debounce"`
+	PressAction       enum.Action   `json:"pressaction" db:"pressaction"`
+	LongPressAction   enum.Action   `json:"longpressaction" db:"longpressaction"`
+	DoublePressAction enum.Action   `json:"doublepressaction" db:"doublepressaction"`
+	ReleaseAction     enum.Action   `json:"releaseaction" db:"releaseaction"`
+}
+
+func (s InputSettings) Validate() error {
+	if !s.InputPolarity.IsValid() {
+		return errors.New("Invalid input polarity")
+	}
+	if !s.InputPull.IsValid() {
+		return errors.New("Invalid input pull")
+	}
+	if s.Debounce < 0 {
+		return errors.New("Debounce must not be negative")
+	}
+	for _, a := range []enum.Action{s.PressAction, s.LongPressAction, s.DoublePressAction, s.ReleaseAction} {
+		if !a.IsValid() {
+			return errors.New("Invalid action")
+		}
+	}
+	return nil
+}
+
+func (s InputSettings) HasActions() bool {
+	return s.PressAction != enum.ActionNone ||
+		s.LongPressAction != enum.ActionNone ||
+		s.DoublePressAction != enum.ActionNone ||
+		s.ReleaseAction != enum.ActionNone
+}
+
+func (s InputSettings) GetAction(gesture enum.Gesture) enum.Action {
+	switch gesture {
+	case enum.GesturePress:
+		return s.PressAction
+	case enum.GestureLongPress:
+		return s.LongPressAction
+	case enum.GestureDoublePress:
+		return s.DoublePressAction
+	case enum.GestureRelease:
+		return s.ReleaseAction
+	default:
+		return enum.ActionNone
+	}
+}
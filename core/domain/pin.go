@@ -1,49 +1,148 @@
 package domain
 
 import (
-	"errors"
-	"math"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
 )
 
 const (
-	McpNumbers       = uint8(8)
-	McpPins          = uint8(16)
-	firstDeviceIndex = McpNumbers * McpPins
+	PinDriverRpio = "rpio"
+	PinDriverMcp  = "mcp"
+	PinDriverPcf  = "pcf"
+
+	// the BCM2835 numbers 54 native pins
+	MaxRpioIndex = 53
+	// the expanders have at most 16 pins, the chips with 8 refuse the rest once opened
+	MaxExpanderIndex = 15
+	// the range of the 7-bit I2C addresses not reserved
+	MinChipAddress = 0x03
+	MaxChipAddress = 0x77
 )
 
-type Pin uint8
+// Pin addresses a native pin of the board or a pin of an IO expander, identified by its I2C bus and address.
+// It is written as "rpio:17" or "mcp:1:0x20:7", the zero value is an unset pin.
+type Pin struct {
+	Driver string
+	Bus    uint8
+	Chip   uint8
+	Index  uint8
+}
+
+func CreateRpioPin(index uint8) Pin {
+	return Pin{Driver: PinDriverRpio, Index: index}
+}
+
+func CreateExpanderPin(driver string, bus, chip, index uint8) Pin {
+	return Pin{Driver: driver, Bus: bus, Chip: chip, Index: index}
+}
+
+func ParsePin(s string) (Pin, error) {
+	if s == "" {
+		return Pin{}, nil
+	}
+	parts := strings.Split(s, ":")
+	var values []uint64
+	for _, part := range parts[1:] {
+		v, err := strconv.ParseUint(part, 0, 8)
+		if err != nil {
+			return Pin{}, fmt.Errorf("Invalid pin '%s'", s)
+		}
+		values = append(values, v)
+	}
+	var pin Pin
+	switch {
+	case parts[0] == PinDriverRpio && len(values) == 1:
+		pin = CreateRpioPin(uint8(values[0]))
+	case (parts[0] == PinDriverMcp || parts[0] == PinDriverPcf) && len(values) == 3:
+		pin = CreateExpanderPin(parts[0], uint8(values[0]), uint8(values[1]), uint8(values[2]))
+	default:
+		return Pin{}, fmt.Errorf("Invalid pin '%s'", s)
+	}
+	if err := pin.Validate(); err != nil {
+		return Pin{}, fmt.Errorf("Invalid pin '%s': %w", s, err)
+	}
+	return pin, nil
+}
 
-func CreatePin(pinIndex uint8) (Pin, error) {
-	if pinIndex > math.MaxUint8-firstDeviceIndex {
-		return 0, errors.New("Invalid pin index")
+// Validate checks the index of a native pin, or the address and the index of an expander pin.
+func (p Pin) Validate() error {
+	switch {
+	case !p.IsSet():
+		return nil
+	case !p.IsExpanderPin():
+		if p.Index > MaxRpioIndex {
+			return fmt.Errorf("Native pin index must be at most %d", MaxRpioIndex)
+		}
+	case p.Chip < MinChipAddress || p.Chip > MaxChipAddress:
+		return fmt.Errorf("Chip address must be between 0x%02x and 0x%02x", MinChipAddress, MaxChipAddress)
+	case p.Index > MaxExpanderIndex:
+		return fmt.Errorf("Expander pin index must be at most %d", MaxExpanderIndex)
 	}
-	return Pin(firstDeviceIndex + pinIndex), nil
+	return nil
+}
+
+func (p Pin) IsSet() bool {
+	return p.Driver != ""
 }
 
-func CreateMcpPin(mcpNumber, pinIndex uint8) (Pin, error) {
-	if mcpNumber >= McpNumbers {
-		return 0, errors.New("Invalid mcp number")
+func (p Pin) IsExpanderPin() bool {
+	return p.IsSet() && p.Driver != PinDriverRpio
+}
+
+// Less orders the pins by driver, bus, chip and index.
+func (p Pin) Less(o Pin) bool {
+	if p.Driver != o.Driver {
+		return p.Driver < o.Driver
+	}
+	if p.Bus != o.Bus {
+		return p.Bus < o.Bus
 	}
-	if pinIndex >= McpPins {
-		return 0, errors.New("Invalid pin index")
+	if p.Chip != o.Chip {
+		return p.Chip < o.Chip
 	}
-	return Pin(mcpNumber*McpPins + pinIndex), nil
+	return p.Index < o.Index
 }
 
-func (p Pin) IsMcpPin() bool {
-	return uint8(p) < firstDeviceIndex
+func (p Pin) String() string {
+	switch {
+	case !p.IsSet():
+		return ""
+	case p.IsExpanderPin():
+		return fmt.Sprintf("%s:%d:0x%02x:%d", p.Driver, p.Bus, p.Chip, p.Index)
+	default:
+		return fmt.Sprintf("%s:%d", p.Driver, p.Index)
+	}
+}
+
+func (p Pin) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
 }
 
-func (p Pin) GetMcpNum() (uint8, error) {
-	if p.IsMcpPin() {
-		return uint8(p) / McpPins, nil
+func (p *Pin) UnmarshalText(text []byte) error {
+	pin, err := ParsePin(string(text))
+	if err != nil {
+		return err
 	}
-	return 0, errors.New("Pin is not an MCP pin")
+	*p = pin
+	return nil
+}
+
+func (p Pin) Value() (driver.Value, error) {
+	return p.String(), nil
 }
 
-func (p Pin) GetPinIndex() uint8 {
-	if p.IsMcpPin() {
-		return uint8(p) % McpPins
+func (p *Pin) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = Pin{}
+		return nil
+	case string:
+		return p.UnmarshalText([]byte(v))
+	case []byte:
+		return p.UnmarshalText(v)
+	default:
+		return fmt.Errorf("Unable to scan pin from %T", src)
 	}
-	return uint8(p) - firstDeviceIndex
 }
@@ -0,0 +1,82 @@
+package domain
+
+import "testing"
+
+func TestParsePin(t *testing.T) {
+	tests := []struct {
+		text string
+		pin  Pin
+		// canonical is the text the pin is written as, when it differs from the parsed one
+		canonical string
+		invalid   bool
+	}{
+		{text: "", pin: Pin{}},
+		{text: "rpio:0", pin: CreateRpioPin(0)},
+		{text: "rpio:17", pin: CreateRpioPin(17)},
+		{text: "rpio:53", pin: CreateRpioPin(MaxRpioIndex)},
+		{text: "rpio:0x11", pin: CreateRpioPin(17), canonical: "rpio:17"},
+		{text: "mcp:1:0x20:7", pin: CreateExpanderPin(PinDriverMcp, 1, 0x20, 7)},
+		{text: "mcp:1:32:0", pin: CreateExpanderPin(PinDriverMcp, 1, 0x20, 0), canonical: "mcp:1:0x20:0"},
+		{text: "pcf:0:0x38:15", pin: CreateExpanderPin(PinDriverPcf, 0, 0x38, 15)},
+		{text: "pcf:255:0x03:0", pin: CreateExpanderPin(PinDriverPcf, 255, MinChipAddress, 0)},
+		{text: "mcp:1:0x77:0", pin: CreateExpanderPin(PinDriverMcp, 1, MaxChipAddress, 0)},
+		{text: "rpio:54", invalid: true},
+		{text: "rpio:255", invalid: true},
+		{text: "rpio:256", invalid: true},
+		{text: "rpio:-1", invalid: true},
+		{text: "rpio:x", invalid: true},
+		{text: "rpio", invalid: true},
+		{text: "rpio:1:2", invalid: true},
+		{text: "mcp:1:0x20", invalid: true},
+		{text: "mcp:1:0x20:16", invalid: true},
+		{text: "mcp:1:0x02:0", invalid: true},
+		{text: "pcf:1:0x78:0", invalid: true},
+		{text: "mcp:256:0x20:0", invalid: true},
+		{text: "gpio:17", invalid: true},
+		{text: "17", invalid: true},
+	}
+	for _, test := range tests {
+		pin, err := ParsePin(test.text)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("ParsePin(%q) = %#v, expected an error", test.text, pin)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePin(%q) failed: %v", test.text, err)
+			continue
+		}
+		if pin != test.pin {
+			t.Errorf("ParsePin(%q) = %#v, expected %#v", test.text, pin, test.pin)
+		}
+		canonical := test.canonical
+		if canonical == "" {
+			canonical = test.text
+		}
+		if s := pin.String(); s != canonical {
+			t.Errorf("%#v is written as %q, expected %q", pin, s, canonical)
+		}
+		if again, err := ParsePin(pin.String()); err != nil || again != pin {
+			t.Errorf("%#v does not round-trip through %q: %#v, %v", pin, pin.String(), again, err)
+		}
+	}
+}
+
+func TestPinScan(t *testing.T) {
+	var pin Pin
+	for _, src := range []interface{}{"mcp:1:0x21:3", []byte("mcp:1:0x21:3")} {
+		if err := pin.Scan(src); err != nil || pin != CreateExpanderPin(PinDriverMcp, 1, 0x21, 3) {
+			t.Errorf("Scan(%v) = %#v, %v", src, pin, err)
+		}
+	}
+	if err := pin.Scan(nil); err != nil || pin.IsSet() {
+		t.Errorf("Scan(nil) = %#v, %v", pin, err)
+	}
+	if err := pin.Scan(int64(17)); err == nil {
+		t.Error("Scan of an integer did not fail")
+	}
+	if err := pin.Scan("rpio:99"); err == nil {
+		t.Error("Scan of an out of range pin did not fail")
+	}
+}
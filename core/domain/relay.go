@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"errors"
+
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+// RelaySettings describe the relay switching a light, a latching relay is pulsed and
+// reports its state through the feedback pin, wired the same way as the wall switch.
+// A dimmable light drives an LED driver instead, fading through the whole range in FadeDuration.
+type RelaySettings struct {
+	PairType      enum.PairType `json:"pairtype" db:"pairtype"`
+	FeedbackPin   Pin           `json:"feedbackpin" db:"feedbackpin" swaggertype:"string"`
+	PulseDuration Milliseconds  `json:"pulseduration" db:"pulseduration"`
+	FadeDuration  Milliseconds  `json:"fadeduration" db:"fadeduration"`
+}
+
+func (s RelaySettings) Validate() error {
+	switch s.PairType {
+	case enum.PairTypeToggle:
+		return nil
+	case enum.PairTypeImpulse:
+		if s.PulseDuration <= 0 {
+			return errors.New("Pulse duration must be positive")
+		}
+		return nil
+	case enum.PairTypeDimmable:
+		if s.FadeDuration < 0 {
+			return errors.New("Fade duration must not be negative")
+		}
+		return nil
+	default:
+		return errors.New("Invalid relay type")
+	}
+}
+
+// GetPins returns the pins used by the relay besides the input and output pins.
+func (s RelaySettings) GetPins() []Pin {
+	if s.PairType == enum.PairTypeImpulse {
+		return []Pin{s.FeedbackPin}
+	}
+	return nil
+}
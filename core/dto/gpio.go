@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+type GpioDiagnostics struct {
+	Pins      []GpioPin      `json:"pins"`
+	Expanders []GpioExpander `json:"expanders"`
+}
+
+type GpioPin struct {
+	Pin   domain.Pin   `json:"pin" swaggertype:"string"`
+	Role  enum.PinRole `json:"role"`
+	Owner string       `json:"owner"`
+	// State is the logical state, Level the one of the wiring
+	State       bool       `json:"state"`
+	Level       bool       `json:"level"`
+	ReadErrors  uint64     `json:"readerrors"`
+	WriteErrors uint64     `json:"writeerrors"`
+	LastRead    *time.Time `json:"lastread"`
+	Degraded    bool       `json:"degraded"`
+}
+
+type GpioExpander struct {
+	Driver     string `json:"driver"`
+	Type       string `json:"type"`
+	Bus        int    `json:"bus"`
+	Address    int    `json:"address"`
+	MuxAddress int    `json:"muxaddress"`
+	MuxChannel uint8  `json:"muxchannel"`
+	Interrupts bool   `json:"interrupts"`
+	Degraded   bool   `json:"degraded"`
+}
+
+// GpioClaim is a pin in use, Owner names the device using it.
+// A pin claimed by a device which failed to register is not Registered.
+type GpioClaim struct {
+	Pin        domain.Pin   `json:"pin" swaggertype:"string"`
+	Role       enum.PinRole `json:"role"`
+	Owner      string       `json:"owner"`
+	Registered bool         `json:"registered"`
+}
+
+// GpioLevel is a raw level change of a watched input.
+type GpioLevel struct {
+	Pin   domain.Pin `json:"pin" swaggertype:"string"`
+	Level bool       `json:"level"`
+	Time  time.Time  `json:"time"`
+}
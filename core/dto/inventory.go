@@ -0,0 +1,15 @@
+package dto
+
+import (
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+// PinAllocation is a pin claimed by a device, Label is the name of the device.
+type PinAllocation struct {
+	Pin      domain.Pin   `json:"pin" db:"pin" swaggertype:"string"`
+	Role     enum.PinRole `json:"role" db:"role"`
+	Device   enum.Device  `json:"device" db:"device"`
+	DeviceId int64        `json:"deviceid" db:"deviceid"`
+	Label    string       `json:"label" db:"label"`
+}
@@ -2,12 +2,20 @@ package dto
 
 import (
 	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
 )
 
 type Light struct {
-	Id        int64      `json:"id"`
-	Name      string     `json:"name"`
-	InputPin  domain.Pin `json:"inputpin"`
-	OutputPin domain.Pin `json:"outputpin"`
-	State  bool       `json:"position"`
+	Id             int64              `json:"id"`
+	Name           string             `json:"name"`
+	InputPin       domain.Pin         `json:"inputpin" swaggertype:"string"`
+	OutputPin      domain.Pin         `json:"outputpin" swaggertype:"string"`
+	OutputPolarity enum.Polarity      `json:"outputpolarity"`
+	RestorePolicy  enum.RestorePolicy `json:"restorepolicy"`
+	State          bool               `json:"position"`
+	Brightness     uint8              `json:"brightness"`
+	Degraded       bool               `json:"degraded"`
+	ExtraInputPins []domain.Pin       `json:"extrainputpins" swaggertype:"array,string"`
+	domain.RelaySettings
+	domain.InputSettings
 }
@@ -1,12 +1,36 @@
 package dto
 
-import "github.com/Erexo/Ventana/core/domain"
+import (
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
 
 type Sunblind struct {
-	Id            int64      `json:"id" db:"id"`
-	Name          string     `json:"name" db:"name"`
-	InputDownPin  domain.Pin `json:"inputdownpin" db:"inputdownpin"`
-	InputUpPin    domain.Pin `json:"inputuppin" db:"inputuppin"`
-	OutputDownPin domain.Pin `json:"outputdownpin" db:"outputdownpin"`
-	OutputUpPin   domain.Pin `json:"outputuppin" db:"outputuppin"`
+	Id             int64               `json:"id" db:"id"`
+	Name           string              `json:"name" db:"name"`
+	SunblindType   enum.SunblindType   `json:"sunblindtype" db:"sunblindtype"`
+	ControlMode    enum.ControlMode    `json:"controlmode" db:"controlmode"`
+	InputPin       domain.Pin          `json:"inputpin" db:"inputpin" swaggertype:"string"`
+	InputDownPin   domain.Pin          `json:"inputdownpin" db:"inputdownpin" swaggertype:"string"`
+	InputUpPin     domain.Pin          `json:"inputuppin" db:"inputuppin" swaggertype:"string"`
+	OutputDownPin  domain.Pin          `json:"outputdownpin" db:"outputdownpin" swaggertype:"string"`
+	OutputUpPin    domain.Pin          `json:"outputuppin" db:"outputuppin" swaggertype:"string"`
+	PulseDuration  domain.Milliseconds `json:"pulseduration" db:"pulseduration"`
+	DeadTime       domain.Milliseconds `json:"deadtime" db:"deadtime"`
+	TravelDownTime domain.Milliseconds `json:"traveldowntime" db:"traveldowntime"`
+	TravelUpTime   domain.Milliseconds `json:"traveluptime" db:"traveluptime"`
+	TiltTime       domain.Milliseconds `json:"tilttime" db:"tilttime"`
+	OutputPolarity enum.Polarity       `json:"outputpolarity" db:"outputpolarity"`
+	Degraded       bool                `json:"degraded" db:"-"`
+	Motion         enum.Motion         `json:"motion" db:"-"`
+	Position       *uint8              `json:"position" db:"-"`
+	Tilt           *uint8              `json:"tilt" db:"-"`
+	domain.InputSettings
+}
+
+type SunblindGroup struct {
+	Id      int64               `json:"id" db:"id"`
+	Name    string              `json:"name" db:"name"`
+	Stagger domain.Milliseconds `json:"stagger" db:"stagger"`
+	Members []int64             `json:"members" db:"-"`
 }
@@ -1,10 +1,17 @@
 package entity
 
-import "github.com/Erexo/Ventana/core/domain"
+import (
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
 
 type Light struct {
-	Id        int64      `db:"id"`
-	Name      string     `db:"name"`
-	InputPin  domain.Pin `db:"inputpin"`
-	OutputPin domain.Pin `db:"outputpin"`
+	Id             int64              `db:"id"`
+	Name           string             `db:"name"`
+	InputPin       domain.Pin         `db:"inputpin"`
+	OutputPin      domain.Pin         `db:"outputpin"`
+	OutputPolarity enum.Polarity      `db:"outputpolarity"`
+	RestorePolicy  enum.RestorePolicy `db:"restorepolicy"`
+	domain.RelaySettings
+	domain.InputSettings
 }
@@ -1,12 +1,18 @@
 package entity
 
-import "github.com/Erexo/Ventana/core/domain"
+import (
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
 
 type Sunblind struct {
-	Id            int64      `db:"id"`
-	Name          string     `db:"name"`
-	InputDownPin  domain.Pin `db:"inputdownpin"`
-	InputUpPin    domain.Pin `db:"inputuppin"`
-	OutputDownPin domain.Pin `db:"outputdownpin"`
-	OutputUpPin   domain.Pin `db:"outputuppin"`
+	Id             int64               `db:"id"`
+	Name           string              `db:"name"`
+	InputDownPin   domain.Pin          `db:"inputdownpin"`
+	InputUpPin     domain.Pin          `db:"inputuppin"`
+	OutputDownPin  domain.Pin          `db:"outputdownpin"`
+	OutputUpPin    domain.Pin          `db:"outputuppin"`
+	PulseDuration  domain.Milliseconds `db:"pulseduration"`
+	OutputPolarity enum.Polarity       `db:"outputpolarity"`
+	domain.InputSettings
 }
@@ -0,0 +1,19 @@
+package enum
+
+// Action is performed by a device when a gesture is recognized on its input.
+type Action uint8
+
+const (
+	ActionNone   Action = 0
+	ActionToggle Action = 1
+	ActionOn     Action = 2
+	ActionOff    Action = 3
+	// ActionHouseOn and ActionHouseOff are whole-house actions, switching every device of the same kind,
+	// e.g. every light of the house, not only the lights of one room.
+	ActionHouseOn  Action = 4
+	ActionHouseOff Action = 5
+)
+
+func (a Action) IsValid() bool {
+	return a <= ActionHouseOff
+}
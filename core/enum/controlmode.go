@@ -0,0 +1,15 @@
+package enum
+
+// ControlMode tells how the wall buttons of a sunblind are wired.
+type ControlMode uint8
+
+const (
+	// ControlModeSeparate has a button for either direction
+	ControlModeSeparate ControlMode = 0
+	// ControlModeSingle cycles up, stop, down, stop with a single button, a long press travels all the way
+	ControlModeSingle ControlMode = 1
+)
+
+func (m ControlMode) IsValid() bool {
+	return m <= ControlModeSingle
+}
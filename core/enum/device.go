@@ -0,0 +1,9 @@
+package enum
+
+// Device is the kind of entity claiming pins.
+type Device string
+
+const (
+	DeviceLight    Device = "light"
+	DeviceSunblind Device = "sunblind"
+)
@@ -0,0 +1,25 @@
+package enum
+
+type Gesture uint8
+
+const (
+	GesturePress       Gesture = 0
+	GestureLongPress   Gesture = 1
+	GestureDoublePress Gesture = 2
+	GestureRelease     Gesture = 3
+)
+
+func (g Gesture) String() string {
+	switch g {
+	case GesturePress:
+		return "press"
+	case GestureLongPress:
+		return "long-press"
+	case GestureDoublePress:
+		return "double-press"
+	case GestureRelease:
+		return "release"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,10 @@
+package enum
+
+// Motion is the way a motor is running.
+type Motion uint8
+
+const (
+	MotionIdle Motion = 0
+	MotionUp   Motion = 1
+	MotionDown Motion = 2
+)
@@ -5,4 +5,9 @@ type PairType uint8
 const (
 	PairTypeToggle PairType = 0
 	PairTypeTimed  PairType = 1
+	// PairTypeImpulse drives a latching relay, a pulse flips it and a feedback input reports its state
+	PairTypeImpulse PairType = 2
+	// PairTypeDimmable drives an LED driver by PWM, fading to the brightness set, a held input dims it.
+	// The output is a native pin, rpio 12, 13, 18 and 19 have a hardware PWM, the others a software one.
+	PairTypeDimmable PairType = 3
 )
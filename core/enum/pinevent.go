@@ -0,0 +1,29 @@
+package enum
+
+type PinEvent uint8
+
+const (
+	// PinEventInput is a debounced level change of an input pin
+	PinEventInput PinEvent = 0
+	// PinEventOutput is a change of the logical state of an output
+	PinEventOutput PinEvent = 1
+	// PinEventError is a failed read or write of a pin
+	PinEventError PinEvent = 2
+	// PinEventLevel is a change of the brightness of a dimmable output
+	PinEventLevel PinEvent = 3
+)
+
+func (e PinEvent) String() string {
+	switch e {
+	case PinEventInput:
+		return "input"
+	case PinEventOutput:
+		return "output"
+	case PinEventError:
+		return "error"
+	case PinEventLevel:
+		return "level"
+	default:
+		return "unknown"
+	}
+}
@@ -1,7 +1,6 @@
 package enum
 
 import (
-	"github.com/racerxdl/go-mcp23017"
 	"github.com/stianeikeland/go-rpio"
 )
 
@@ -10,18 +9,17 @@ type PinMode uint8
 const (
 	PinModeInput PinMode = iota
 	PinModeOutput
+	// PinModePwm is an output driven by the hardware PWM of the pin
+	PinModePwm
 )
 
 func (p PinMode) GetRpioMode() rpio.Mode {
-	if p == PinModeInput {
+	switch p {
+	case PinModeInput:
 		return rpio.Input
+	case PinModePwm:
+		return rpio.Pwm
+	default:
+		return rpio.Output
 	}
-	return rpio.Output
-}
-
-func (p PinMode) GetMcpMode() mcp23017.PinMode {
-	if p == PinModeInput {
-		return mcp23017.INPUT
-	}
-	return mcp23017.OUTPUT
 }
@@ -0,0 +1,11 @@
+package enum
+
+// PinRole is the use of a pin by its device.
+type PinRole string
+
+const (
+	PinRoleInput      PinRole = "input"
+	PinRoleOutput     PinRole = "output"
+	PinRoleFeedback   PinRole = "feedback"
+	PinRoleBoundInput PinRole = "bound input"
+)
@@ -0,0 +1,23 @@
+package enum
+
+// Polarity tells which level of a pin means "on".
+type Polarity uint8
+
+const (
+	PolarityActiveLow  Polarity = 0
+	PolarityActiveHigh Polarity = 1
+)
+
+func (p Polarity) IsValid() bool {
+	return p <= PolarityActiveHigh
+}
+
+// GetLevel returns the level of a pin in the given logical state.
+func (p Polarity) GetLevel(active bool) bool {
+	return active == (p == PolarityActiveHigh)
+}
+
+// IsActive returns the logical state of a pin at the given level.
+func (p Polarity) IsActive(level bool) bool {
+	return level == (p == PolarityActiveHigh)
+}
@@ -0,0 +1,26 @@
+package enum
+
+import "github.com/stianeikeland/go-rpio"
+
+type Pull uint8
+
+const (
+	PullUp   Pull = 0
+	PullNone Pull = 1
+	PullDown Pull = 2
+)
+
+func (p Pull) IsValid() bool {
+	return p <= PullDown
+}
+
+func (p Pull) GetRpioPull() rpio.Pull {
+	switch p {
+	case PullUp:
+		return rpio.PullUp
+	case PullDown:
+		return rpio.PullDown
+	default:
+		return rpio.PullOff
+	}
+}
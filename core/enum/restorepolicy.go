@@ -0,0 +1,26 @@
+package enum
+
+// RestorePolicy decides the state a device starts in after a restart.
+type RestorePolicy uint8
+
+const (
+	RestorePolicyOff  RestorePolicy = 0
+	RestorePolicyOn   RestorePolicy = 1
+	RestorePolicyLast RestorePolicy = 2
+)
+
+func (p RestorePolicy) IsValid() bool {
+	return p <= RestorePolicyLast
+}
+
+// GetState returns the initial state of the device, given the state it was left in.
+func (p RestorePolicy) GetState(last bool) bool {
+	switch p {
+	case RestorePolicyOn:
+		return true
+	case RestorePolicyLast:
+		return last
+	default:
+		return false
+	}
+}
@@ -0,0 +1,13 @@
+package enum
+
+type SunblindType uint8
+
+const (
+	SunblindTypeRoller SunblindType = 0
+	// SunblindTypeVenetian turns its slats before it starts to travel either way
+	SunblindTypeVenetian SunblindType = 1
+)
+
+func (t SunblindType) IsValid() bool {
+	return t <= SunblindTypeVenetian
+}
@@ -24,16 +24,33 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/light/browse": {
-            "post": {
+        "/api/gpio": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "consumes": [
+                "produces": [
                     "application/json"
                 ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GpioDiagnostics"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/gpio/claims": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
@@ -43,14 +60,14 @@ var doc = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/dto.Light"
+                                "$ref": "#/definitions/dto.GpioClaim"
                             }
                         }
                     }
                 }
             }
         },
-        "/api/light/create": {
+        "/api/gpio/pulse": {
             "post": {
                 "security": [
                     {
@@ -70,7 +87,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/light.saveDto"
+                            "$ref": "#/definitions/gpio.pulseDto"
                         }
                     }
                 ],
@@ -84,76 +101,77 @@ var doc = `{
                 }
             }
         },
-        "/api/light/delete/{id}": {
-            "delete": {
+        "/api/gpio/watch/{pin}": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "produces": [
+                    "application/json"
+                ],
                 "parameters": [
                     {
-                        "type": "integer",
+                        "type": "string",
                         "description": "path",
-                        "name": "id",
+                        "name": "pin",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "query",
+                        "name": "pull",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/dto.GpioLevel"
                         }
                     }
                 }
             }
         },
-        "/api/light/order": {
-            "post": {
+        "/api/inventory": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
-                "parameters": [
-                    {
-                        "description": "body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "integer"
-                            }
-                        }
-                    }
-                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.PinAllocation"
+                            }
                         }
                     }
                 }
             }
         },
-        "/api/light/toggle/{id}": {
+        "/api/light/brightness/{id}": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
                 "parameters": [
                     {
                         "type": "integer",
@@ -161,6 +179,15 @@ var doc = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/light.brightnessDto"
+                        }
                     }
                 ],
                 "responses": {
@@ -173,8 +200,8 @@ var doc = `{
                 }
             }
         },
-        "/api/light/update/{id}": {
-            "patch": {
+        "/api/light/browse": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
@@ -184,43 +211,33 @@ var doc = `{
                     "application/json"
                 ],
                 "produces": [
-                    "text/plain"
-                ],
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "path",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/light.saveDto"
-                        }
-                    }
+                    "application/json"
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.Light"
+                            }
                         }
                     }
                 }
             }
         },
-        "/api/login": {
+        "/api/light/create": {
             "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "parameters": [
                     {
@@ -229,7 +246,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/user.loginDto"
+                            "$ref": "#/definitions/light.saveDto"
                         }
                     }
                 ],
@@ -237,39 +254,39 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/user.LoginInfo"
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/api/sunblind/browse": {
-            "post": {
+        "/api/light/delete/{id}": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dto.Sunblind"
-                            }
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/api/sunblind/create": {
+        "/api/light/input/{id}": {
             "post": {
                 "security": [
                     {
@@ -283,13 +300,20 @@ var doc = `{
                     "text/plain"
                 ],
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "description": "body",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/sunblind.saveDto"
+                            "$ref": "#/definitions/light.inputDto"
                         }
                     }
                 ],
@@ -303,7 +327,7 @@ var doc = `{
                 }
             }
         },
-        "/api/sunblind/delete/{id}": {
+        "/api/light/input/{id}/{pin}": {
             "delete": {
                 "security": [
                     {
@@ -317,6 +341,13 @@ var doc = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "path",
+                        "name": "pin",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -329,7 +360,7 @@ var doc = `{
                 }
             }
         },
-        "/api/sunblind/order": {
+        "/api/light/order": {
             "post": {
                 "security": [
                     {
@@ -366,7 +397,7 @@ var doc = `{
                 }
             }
         },
-        "/api/sunblind/toggle/{id}/{dir}": {
+        "/api/light/toggle/{id}": {
             "post": {
                 "security": [
                     {
@@ -380,13 +411,6 @@ var doc = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "path",
-                        "name": "dir",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
@@ -399,7 +423,7 @@ var doc = `{
                 }
             }
         },
-        "/api/sunblind/update/{id}": {
+        "/api/light/update/{id}": {
             "patch": {
                 "security": [
                     {
@@ -426,7 +450,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/sunblind.saveDto"
+                            "$ref": "#/definitions/light.saveDto"
                         }
                     }
                 ],
@@ -440,33 +464,36 @@ var doc = `{
                 }
             }
         },
-        "/api/thermal/browse": {
+        "/api/login": {
             "post": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.loginDto"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dto.Thermometer"
-                            }
+                            "$ref": "#/definitions/user.LoginInfo"
                         }
                     }
                 }
             }
         },
-        "/api/thermal/create": {
+        "/api/sunblind/browse": {
             "post": {
                 "security": [
                     {
@@ -477,30 +504,22 @@ var doc = `{
                     "application/json"
                 ],
                 "produces": [
-                    "text/plain"
-                ],
-                "parameters": [
-                    {
-                        "description": "body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/thermal.saveDto"
-                        }
-                    }
+                    "application/json"
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.Sunblind"
+                            }
                         }
                     }
                 }
             }
         },
-        "/api/thermal/data": {
+        "/api/sunblind/create": {
             "post": {
                 "security": [
                     {
@@ -511,7 +530,7 @@ var doc = `{
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "parameters": [
                     {
@@ -520,7 +539,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/thermal.dataDto"
+                            "$ref": "#/definitions/sunblind.saveDto"
                         }
                     }
                 ],
@@ -528,16 +547,13 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dto.Point"
-                            }
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/api/thermal/delete/{id}": {
+        "/api/sunblind/delete/{id}": {
             "delete": {
                 "security": [
                     {
@@ -563,7 +579,7 @@ var doc = `{
                 }
             }
         },
-        "/api/thermal/order": {
+        "/api/sunblind/group/browse": {
             "post": {
                 "security": [
                     {
@@ -576,32 +592,21 @@ var doc = `{
                 "produces": [
                     "application/json"
                 ],
-                "parameters": [
-                    {
-                        "description": "body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "integer"
-                            }
-                        }
-                    }
-                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.SunblindGroup"
+                            }
                         }
                     }
                 }
             }
         },
-        "/api/thermal/update/{id}": {
-            "patch": {
+        "/api/sunblind/group/create": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
@@ -614,20 +619,13 @@ var doc = `{
                     "text/plain"
                 ],
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "path",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "description": "body",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/thermal.saveDto"
+                            "$ref": "#/definitions/sunblind.groupDto"
                         }
                     }
                 ],
@@ -641,44 +639,211 @@ var doc = `{
                 }
             }
         },
-        "/api/user/browse": {
-            "post": {
+        "/api/sunblind/group/delete/{id}": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "consumes": [
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sunblind/group/move/{id}/{dir}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "path",
+                        "name": "dir",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sunblind/group/position/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
                     "application/json"
                 ],
                 "produces": [
+                    "text/plain"
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/sunblind.positionDto"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sunblind/group/stop/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sunblind/group/update/{id}": {
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "text/plain"
+                ],
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "description": "body",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.Filters"
+                            "$ref": "#/definitions/sunblind.groupDto"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sunblind/order": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/dto.User"
+                                "type": "integer"
                             }
                         }
                     }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
                 }
             }
         },
-        "/api/user/create": {
+        "/api/sunblind/position/{id}": {
             "post": {
                 "security": [
                     {
@@ -692,13 +857,20 @@ var doc = `{
                     "text/plain"
                 ],
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "description": "body",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/user.createDto"
+                            "$ref": "#/definitions/sunblind.positionDto"
                         }
                     }
                 ],
@@ -712,8 +884,8 @@ var doc = `{
                 }
             }
         },
-        "/api/user/delete/{id}": {
-            "delete": {
+        "/api/sunblind/stop/{id}": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
@@ -738,13 +910,19 @@ var doc = `{
                 }
             }
         },
-        "/api/user/update/password/{id}": {
-            "patch": {
+        "/api/sunblind/tilt/{id}": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
                 "parameters": [
                     {
                         "type": "integer",
@@ -759,7 +937,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/user.updatePasswordDto"
+                            "$ref": "#/definitions/sunblind.tiltDto"
                         }
                     }
                 ],
@@ -773,13 +951,52 @@ var doc = `{
                 }
             }
         },
-        "/api/user/update/role/{id}": {
+        "/api/sunblind/toggle/{id}/{dir}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "path",
+                        "name": "dir",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sunblind/update/{id}": {
             "patch": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
                 "parameters": [
                     {
                         "type": "integer",
@@ -794,7 +1011,7 @@ var doc = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/user.updateRoleDto"
+                            "$ref": "#/definitions/sunblind.saveDto"
                         }
                     }
                 ],
@@ -807,43 +1024,592 @@ var doc = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dto.Filters": {
-            "type": "object",
-            "properties": {
-                "Column": {
-                    "type": "string"
-                },
-                "Count": {
-                    "type": "integer"
-                },
-                "Descending": {
-                    "type": "boolean"
-                },
-                "Offset": {
-                    "type": "integer"
-                }
-            }
         },
-        "dto.Light": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "integer"
-                },
+        "/api/thermal/browse": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.Thermometer"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/thermal/create": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/thermal.saveDto"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/thermal/data": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/thermal.dataDto"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.Point"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/thermal/delete/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/thermal/order": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/thermal/update/{id}": {
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/thermal.saveDto"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/browse": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.Filters"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.User"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/create": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.createDto"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/delete/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/update/password/{id}": {
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.updatePasswordDto"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/update/role/{id}": {
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "path",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.updateRoleDto"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.Filters": {
+            "type": "object",
+            "properties": {
+                "Column": {
+                    "type": "string"
+                },
+                "Count": {
+                    "type": "integer"
+                },
+                "Descending": {
+                    "type": "boolean"
+                },
+                "Offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.GpioClaim": {
+            "type": "object",
+            "properties": {
+                "owner": {
+                    "type": "string"
+                },
+                "pin": {
+                    "type": "string"
+                },
+                "registered": {
+                    "type": "boolean"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.GpioDiagnostics": {
+            "type": "object",
+            "properties": {
+                "expanders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.GpioExpander"
+                    }
+                },
+                "pins": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.GpioPin"
+                    }
+                }
+            }
+        },
+        "dto.GpioExpander": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "integer"
+                },
+                "bus": {
+                    "type": "integer"
+                },
+                "degraded": {
+                    "type": "boolean"
+                },
+                "driver": {
+                    "type": "string"
+                },
+                "interrupts": {
+                    "type": "boolean"
+                },
+                "muxaddress": {
+                    "type": "integer"
+                },
+                "muxchannel": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.GpioLevel": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "boolean"
+                },
+                "pin": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.GpioPin": {
+            "type": "object",
+            "properties": {
+                "degraded": {
+                    "type": "boolean"
+                },
+                "lastread": {
+                    "type": "string"
+                },
+                "level": {
+                    "type": "boolean"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "pin": {
+                    "type": "string"
+                },
+                "readerrors": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "state": {
+                    "description": "State is the logical state, Level the one of the wiring",
+                    "type": "boolean"
+                },
+                "writeerrors": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.Light": {
+            "type": "object",
+            "properties": {
+                "brightness": {
+                    "type": "integer"
+                },
+                "debounce": {
+                    "type": "integer"
+                },
+                "degraded": {
+                    "type": "boolean"
+                },
+                "doublepressaction": {
+                    "type": "integer"
+                },
+                "extrainputpins": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "fadeduration": {
+                    "type": "integer"
+                },
+                "feedbackpin": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
                 "inputpin": {
+                    "type": "string"
+                },
+                "inputpolarity": {
+                    "type": "integer"
+                },
+                "inputpull": {
+                    "type": "integer"
+                },
+                "longpressaction": {
                     "type": "integer"
                 },
                 "name": {
                     "type": "string"
                 },
                 "outputpin": {
+                    "type": "string"
+                },
+                "outputpolarity": {
+                    "type": "integer"
+                },
+                "pairtype": {
                     "type": "integer"
                 },
                 "position": {
                     "type": "boolean"
+                },
+                "pressaction": {
+                    "type": "integer"
+                },
+                "pulseduration": {
+                    "type": "integer"
+                },
+                "releaseaction": {
+                    "type": "integer"
+                },
+                "restorepolicy": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.PinAllocation": {
+            "type": "object",
+            "properties": {
+                "device": {
+                    "type": "string"
+                },
+                "deviceid": {
+                    "type": "integer"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "pin": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
                 }
             }
         },
@@ -861,22 +1627,102 @@ var doc = `{
         "dto.Sunblind": {
             "type": "object",
             "properties": {
+                "controlmode": {
+                    "type": "integer"
+                },
+                "deadtime": {
+                    "type": "integer"
+                },
+                "debounce": {
+                    "type": "integer"
+                },
+                "degraded": {
+                    "type": "boolean"
+                },
+                "doublepressaction": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "integer"
                 },
                 "inputdownpin": {
+                    "type": "string"
+                },
+                "inputpin": {
+                    "type": "string"
+                },
+                "inputpolarity": {
+                    "type": "integer"
+                },
+                "inputpull": {
                     "type": "integer"
                 },
                 "inputuppin": {
+                    "type": "string"
+                },
+                "longpressaction": {
+                    "type": "integer"
+                },
+                "motion": {
                     "type": "integer"
                 },
                 "name": {
                     "type": "string"
                 },
                 "outputdownpin": {
+                    "type": "string"
+                },
+                "outputpolarity": {
                     "type": "integer"
                 },
                 "outputuppin": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "pressaction": {
+                    "type": "integer"
+                },
+                "pulseduration": {
+                    "type": "integer"
+                },
+                "releaseaction": {
+                    "type": "integer"
+                },
+                "sunblindtype": {
+                    "type": "integer"
+                },
+                "tilt": {
+                    "type": "integer"
+                },
+                "tilttime": {
+                    "type": "integer"
+                },
+                "traveldowntime": {
+                    "type": "integer"
+                },
+                "traveluptime": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.SunblindGroup": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "stagger": {
                     "type": "integer"
                 }
             }
@@ -912,16 +1758,113 @@ var doc = `{
                 }
             }
         },
+        "gpio.pulseDto": {
+            "type": "object",
+            "properties": {
+                "pin": {
+                    "type": "string"
+                },
+                "polarity": {
+                    "type": "integer"
+                },
+                "seconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "light.brightnessDto": {
+            "type": "object",
+            "properties": {
+                "brightness": {
+                    "type": "integer"
+                }
+            }
+        },
+        "light.inputDto": {
+            "type": "object",
+            "properties": {
+                "inputpin": {
+                    "type": "string"
+                }
+            }
+        },
         "light.saveDto": {
             "type": "object",
             "properties": {
+                "debounce": {
+                    "type": "integer"
+                },
+                "doublepressaction": {
+                    "type": "integer"
+                },
+                "fadeduration": {
+                    "type": "integer"
+                },
+                "feedbackpin": {
+                    "type": "string"
+                },
                 "inputpin": {
+                    "type": "string"
+                },
+                "inputpolarity": {
+                    "type": "integer"
+                },
+                "inputpull": {
+                    "type": "integer"
+                },
+                "longpressaction": {
                     "type": "integer"
                 },
                 "name": {
                     "type": "string"
                 },
                 "outputpin": {
+                    "type": "string"
+                },
+                "outputpolarity": {
+                    "type": "integer"
+                },
+                "pairtype": {
+                    "type": "integer"
+                },
+                "pressaction": {
+                    "type": "integer"
+                },
+                "pulseduration": {
+                    "type": "integer"
+                },
+                "releaseaction": {
+                    "type": "integer"
+                },
+                "restorepolicy": {
+                    "type": "integer"
+                }
+            }
+        },
+        "sunblind.groupDto": {
+            "type": "object",
+            "properties": {
+                "members": {
+                    "description": "the members in the order they are moved",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "stagger": {
+                    "description": "milliseconds between the members starting to move, all at once when not set",
+                    "type": "integer"
+                }
+            }
+        },
+        "sunblind.positionDto": {
+            "type": "object",
+            "properties": {
+                "position": {
+                    "description": "percent closed",
                     "type": "integer"
                 }
             }
@@ -929,19 +1872,76 @@ var doc = `{
         "sunblind.saveDto": {
             "type": "object",
             "properties": {
+                "controlmode": {
+                    "type": "integer"
+                },
+                "deadtime": {
+                    "type": "integer"
+                },
+                "debounce": {
+                    "type": "integer"
+                },
+                "doublepressaction": {
+                    "type": "integer"
+                },
                 "inputdownpin": {
+                    "type": "string"
+                },
+                "inputpin": {
+                    "type": "string"
+                },
+                "inputpolarity": {
+                    "type": "integer"
+                },
+                "inputpull": {
                     "type": "integer"
                 },
                 "inputuppin": {
+                    "type": "string"
+                },
+                "longpressaction": {
                     "type": "integer"
                 },
                 "name": {
                     "type": "string"
                 },
                 "outputdownpin": {
+                    "type": "string"
+                },
+                "outputpolarity": {
                     "type": "integer"
                 },
                 "outputuppin": {
+                    "type": "string"
+                },
+                "pressaction": {
+                    "type": "integer"
+                },
+                "pulseduration": {
+                    "type": "integer"
+                },
+                "releaseaction": {
+                    "type": "integer"
+                },
+                "sunblindtype": {
+                    "type": "integer"
+                },
+                "tilttime": {
+                    "type": "integer"
+                },
+                "traveldowntime": {
+                    "type": "integer"
+                },
+                "traveluptime": {
+                    "type": "integer"
+                }
+            }
+        },
+        "sunblind.tiltDto": {
+            "type": "object",
+            "properties": {
+                "tilt": {
+                    "description": "percent closed, the slats are open at 0",
                     "type": "integer"
                 }
             }
@@ -12,13 +12,28 @@ import (
 const configFile = "config.json"
 
 type Configuration struct {
-	DatabaseFile              null.String `json:"databasefile"`
-	JwtToken                  string      `json:"jwttoken"`
-	ApiAddr                   null.String `json:"apiaddr"`
-	UseSwagger                bool        `json:"useswagger"`
-	UseWebDir                 bool        `json:"usewebdir"`
-	ThermalUpdateInterval     int         `json:"thermalupdateinterval"`
-	GenerateRandomTemperature bool        `json:"GenerateRandomTemperature"`
+	DatabaseFile              null.String      `json:"databasefile"`
+	JwtToken                  string           `json:"jwttoken"`
+	ApiAddr                   null.String      `json:"apiaddr"`
+	UseSwagger                bool             `json:"useswagger"`
+	UseWebDir                 bool             `json:"usewebdir"`
+	ThermalUpdateInterval     int              `json:"thermalupdateinterval"`
+	GenerateRandomTemperature bool             `json:"GenerateRandomTemperature"`
+	GpioDriver                string           `json:"gpiodriver"`
+	GpioInputMode             string           `json:"gpioinputmode"`
+	Expanders                 []ExpanderConfig `json:"expanders"`
+}
+
+// ExpanderConfig describes the IO expander found by the pins at its Bus and Address.
+// MuxAddress is set when the chip sits behind an I2C multiplexer, on its MuxChannel,
+// InterruptPin is the native pin wired to its INT output.
+type ExpanderConfig struct {
+	Type         string   `json:"type"`
+	Bus          int      `json:"bus"`
+	Address      int      `json:"address"`
+	MuxAddress   int      `json:"muxaddress"`
+	MuxChannel   uint8    `json:"muxchannel"`
+	InterruptPin null.Int `json:"interruptpin"`
 }
 
 var instance *Configuration
@@ -40,6 +55,10 @@ func GetConfig() Configuration {
 		if err = json.Unmarshal(config, c); err != nil {
 			return *defaultConfiguration()
 		}
+		if c.ThermalUpdateInterval <= 0 {
+			c.ThermalUpdateInterval = defaultConfiguration().ThermalUpdateInterval
+			log.Printf("Thermal update interval must be positive, using %dms\n", c.ThermalUpdateInterval)
+		}
 		instance = c
 		log.Println("Loaded configuration")
 	}
@@ -55,5 +74,8 @@ func defaultConfiguration() *Configuration {
 		UseWebDir:                 true,
 		ThermalUpdateInterval:     60000,
 		GenerateRandomTemperature: false,
+		GpioDriver:                "hardware",
+		GpioInputMode:             "interrupt",
+		Expanders:                 []ExpanderConfig{},
 	}
 }
@@ -18,11 +18,18 @@ func Initialize() error {
 	_, err := os.Stat(getDatabasePath())
 	if err == nil {
 		log.Println("Database is already initialized")
-		return nil
+		return migrate()
 	} else if !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
 
+	if err := create(); err != nil {
+		return err
+	}
+	return migrate()
+}
+
+func create() error {
 	conn, err := GetConnection()
 	if err != nil {
 		return err
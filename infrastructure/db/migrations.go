@@ -0,0 +1,238 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+type migration func(tx *sql.Tx) error
+
+// migrations are applied in order on top of the initial schema,
+// the number of already applied ones is kept in the user_version pragma.
+var migrations = []migration{
+	// debounce and gesture actions of the wall switches
+	statement(`ALTER TABLE light ADD COLUMN debounce INTEGER NOT NULL DEFAULT 50;
+	ALTER TABLE light ADD COLUMN pressaction INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE light ADD COLUMN longpressaction INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE light ADD COLUMN doublepressaction INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE light ADD COLUMN releaseaction INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sunblind ADD COLUMN debounce INTEGER NOT NULL DEFAULT 50;
+	ALTER TABLE sunblind ADD COLUMN pressaction INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sunblind ADD COLUMN longpressaction INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sunblind ADD COLUMN doublepressaction INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sunblind ADD COLUMN releaseaction INTEGER NOT NULL DEFAULT 0;`),
+	// pulse duration of the timed pairs, in milliseconds
+	statement(`ALTER TABLE sunblind ADD COLUMN pulseduration INTEGER NOT NULL DEFAULT 2000;`),
+	// polarity of the pins, active-low with pull-up inputs as the boards used so far
+	statement(`ALTER TABLE light ADD COLUMN outputpolarity INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE light ADD COLUMN inputpolarity INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE light ADD COLUMN inputpull INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sunblind ADD COLUMN outputpolarity INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sunblind ADD COLUMN inputpolarity INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sunblind ADD COLUMN inputpull INTEGER NOT NULL DEFAULT 0;`),
+	// latching relays of the lights, pulsed and reporting through a feedback pin
+	statement(`ALTER TABLE light ADD COLUMN pairtype INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE light ADD COLUMN feedbackpin INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE light ADD COLUMN pulseduration INTEGER NOT NULL DEFAULT 200;`),
+	// additional wall switches of the lights
+	statement(`CREATE TABLE lightinput (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		lightid INTEGER NOT NULL REFERENCES light(id) ON DELETE CASCADE,
+		inputpin INTEGER NOT NULL,
+		UNIQUE(lightid, inputpin)
+	);`),
+	// state of the lights after a restart
+	statement(`ALTER TABLE light ADD COLUMN restorepolicy INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE light ADD COLUMN laststate INTEGER NOT NULL DEFAULT 0;`),
+	// structured pin addresses, stored as text in the former integer columns
+	convertPins,
+	// dimmable lights
+	statement(`ALTER TABLE light ADD COLUMN fadeduration INTEGER NOT NULL DEFAULT 1000;
+	ALTER TABLE light ADD COLUMN brightness INTEGER NOT NULL DEFAULT 100;`),
+	// pause of the sunblind motors before reversing, in milliseconds
+	statement(`ALTER TABLE sunblind ADD COLUMN deadtime INTEGER NOT NULL DEFAULT 500;`),
+	// inventory of the pins claimed by the devices
+	createPinInventory,
+	// full travel times of the sunblinds, in milliseconds, and their last estimated position in percent closed
+	statement(`ALTER TABLE sunblind ADD COLUMN traveldowntime INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sunblind ADD COLUMN traveluptime INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sunblind ADD COLUMN position REAL NOT NULL DEFAULT 0;`),
+	// venetian blinds, with the time their slats take to turn and the last estimated tilt in percent closed
+	statement(`ALTER TABLE sunblind ADD COLUMN sunblindtype INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sunblind ADD COLUMN tilttime INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sunblind ADD COLUMN tilt REAL NOT NULL DEFAULT 0;`),
+	// sunblinds driven by a single button, the buttons of the directions become optional
+	statement(`ALTER TABLE sunblind ADD COLUMN controlmode INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sunblind ADD COLUMN inputpin TEXT NOT NULL DEFAULT '';`),
+	// named groups of sunblinds commanded at once, the stagger in milliseconds between the members
+	statement(`CREATE TABLE sunblindgroup (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		name TEXT UNIQUE NOT NULL,
+		stagger INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE TABLE sunblindgroupmember (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		groupid INTEGER NOT NULL REFERENCES sunblindgroup(id) ON DELETE CASCADE,
+		sunblindid INTEGER NOT NULL REFERENCES sunblind(id) ON DELETE CASCADE,
+		UNIQUE(groupid, sunblindid)
+	);`),
+}
+
+func statement(query string) migration {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+var pinColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"light", []string{"inputpin", "outputpin", "feedbackpin"}},
+	{"lightinput", []string{"inputpin"}},
+	{"sunblind", []string{"inputdownpin", "inputuppin", "outputdownpin", "outputuppin"}},
+}
+
+func convertPins(tx *sql.Tx) error {
+	// only the latching relays have a feedback pin
+	if _, err := tx.Exec("UPDATE light SET feedbackpin='' WHERE pairtype<>?", enum.PairTypeImpulse); err != nil {
+		return err
+	}
+	for _, t := range pinColumns {
+		for _, column := range t.columns {
+			query := fmt.Sprintf("SELECT id, %s FROM %s WHERE typeof(%s)='integer'", column, t.table, column)
+			rows, err := tx.Query(query)
+			if err != nil {
+				return err
+			}
+			pins := make(map[int64]domain.Pin)
+			for rows.Next() {
+				var id, pin int64
+				if err := rows.Scan(&id, &pin); err != nil {
+					rows.Close()
+					return err
+				}
+				converted, err := convertLegacyPin(pin)
+				if err != nil {
+					// the device is left without the pin, it fails to register until it is assigned again
+					log.Printf("%s of %s '%d': %v, the pin is unset\n", column, t.table, id, err)
+				}
+				pins[id] = converted
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			update := fmt.Sprintf("UPDATE %s SET %s=? WHERE id=?", t.table, column)
+			for id, pin := range pins {
+				if _, err := tx.Exec(update, pin, id); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pinClaims select the pins claimed by the devices so far, with the ids and names of the devices. Unset pins are left out.
+var pinClaims = []struct {
+	role   enum.PinRole
+	device enum.Device
+	query  string
+}{
+	{enum.PinRoleInput, enum.DeviceLight, "SELECT inputpin AS pin, id, name FROM light"},
+	{enum.PinRoleOutput, enum.DeviceLight, "SELECT outputpin AS pin, id, name FROM light"},
+	{enum.PinRoleFeedback, enum.DeviceLight, "SELECT feedbackpin AS pin, id, name FROM light"},
+	{enum.PinRoleBoundInput, enum.DeviceLight, "SELECT lightinput.inputpin AS pin, light.id, light.name FROM lightinput JOIN light ON light.id=lightinput.lightid"},
+	{enum.PinRoleInput, enum.DeviceSunblind, "SELECT inputdownpin AS pin, id, name FROM sunblind"},
+	{enum.PinRoleInput, enum.DeviceSunblind, "SELECT inputuppin AS pin, id, name FROM sunblind"},
+	{enum.PinRoleOutput, enum.DeviceSunblind, "SELECT outputdownpin AS pin, id, name FROM sunblind"},
+	{enum.PinRoleOutput, enum.DeviceSunblind, "SELECT outputuppin AS pin, id, name FROM sunblind"},
+}
+
+// createPinInventory records the pins of the existing devices. The conflicting assignments written before
+// are kept, so they show in the allocation, and logged to be fixed.
+func createPinInventory(tx *sql.Tx) error {
+	if _, err := tx.Exec(`CREATE TABLE pin (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		pin TEXT NOT NULL,
+		role TEXT NOT NULL,
+		device TEXT NOT NULL,
+		deviceid INTEGER NOT NULL,
+		label TEXT NOT NULL
+	);
+	CREATE INDEX pin_pin ON pin(pin);
+	CREATE INDEX pin_device ON pin(device, deviceid);`); err != nil {
+		return err
+	}
+	for _, c := range pinClaims {
+		query := fmt.Sprintf("INSERT INTO pin (pin, role, device, deviceid, label) SELECT pin, ?, ?, id, name FROM (%s) WHERE pin<>''", c.query)
+		if _, err := tx.Exec(query, c.role, c.device); err != nil {
+			return err
+		}
+	}
+	rows, err := tx.Query(`SELECT pin, group_concat(device || ' ' || deviceid, ', ') FROM pin
+		GROUP BY pin HAVING count(*)>1 AND count(*)<>sum(role=?)`, enum.PinRoleBoundInput)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pin, devices string
+		if err := rows.Scan(&pin, &devices); err != nil {
+			return err
+		}
+		log.Printf("Pin %s is assigned to more than one device: %s\n", pin, devices)
+	}
+	return rows.Err()
+}
+
+// convertLegacyPin translates the former numbering, where the first 128 pins were the 16 pins of
+// the MCP23017s at addresses 0x20-0x27 of bus 1, followed by the native pins.
+func convertLegacyPin(pin int64) (domain.Pin, error) {
+	switch {
+	case pin >= 0 && pin < 128:
+		return domain.CreateExpanderPin(domain.PinDriverMcp, 1, uint8(0x20+pin/16), uint8(pin%16)), nil
+	case pin >= 128 && pin-128 <= domain.MaxRpioIndex:
+		return domain.CreateRpioPin(uint8(pin - 128)), nil
+	default:
+		return domain.Pin{}, fmt.Errorf("Legacy pin %d is out of range", pin)
+	}
+}
+
+func migrate() error {
+	conn, err := GetConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var version int
+	if err := conn.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := conn.Begin()
+		if err != nil {
+			return err
+		}
+		if err := migrations[i](tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("Applied database migration %d\n", i+1)
+	}
+	return nil
+}
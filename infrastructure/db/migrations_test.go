@@ -0,0 +1,42 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/Erexo/Ventana/core/domain"
+)
+
+func TestConvertLegacyPin(t *testing.T) {
+	tests := []struct {
+		legacy  int64
+		pin     domain.Pin
+		invalid bool
+	}{
+		{legacy: 0, pin: domain.CreateExpanderPin(domain.PinDriverMcp, 1, 0x20, 0)},
+		{legacy: 15, pin: domain.CreateExpanderPin(domain.PinDriverMcp, 1, 0x20, 15)},
+		{legacy: 16, pin: domain.CreateExpanderPin(domain.PinDriverMcp, 1, 0x21, 0)},
+		{legacy: 127, pin: domain.CreateExpanderPin(domain.PinDriverMcp, 1, 0x27, 15)},
+		{legacy: 128, pin: domain.CreateRpioPin(0)},
+		{legacy: 145, pin: domain.CreateRpioPin(17)},
+		{legacy: 128 + domain.MaxRpioIndex, pin: domain.CreateRpioPin(domain.MaxRpioIndex)},
+		{legacy: 128 + domain.MaxRpioIndex + 1, invalid: true},
+		{legacy: 255, invalid: true},
+		{legacy: -1, invalid: true},
+	}
+	for _, test := range tests {
+		pin, err := convertLegacyPin(test.legacy)
+		if test.invalid {
+			if err == nil || pin.IsSet() {
+				t.Errorf("convertLegacyPin(%d) = %v, %v, expected an unset pin and an error", test.legacy, pin, err)
+			}
+			continue
+		}
+		if err != nil || pin != test.pin {
+			t.Errorf("convertLegacyPin(%d) = %v, %v, expected %v", test.legacy, pin, err, test.pin)
+		}
+		// the converted pins are stored in their text form and read back
+		if err := pin.Validate(); err != nil {
+			t.Errorf("convertLegacyPin(%d) = %v is invalid: %v", test.legacy, pin, err)
+		}
+	}
+}
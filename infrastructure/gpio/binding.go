@@ -0,0 +1,163 @@
+package gpio
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+// binding is an additional input driving the outputs of one or more pairs, like the other switches of
+// a staircase light or a button switching a whole group.
+type binding struct {
+	// targets are the input pins of the bound pairs with their gesture handlers, nil for a direct drive
+	targets  map[domain.Pin]func(enum.Gesture)
+	owners   map[domain.Pin]string
+	gestures *gestureRecognizer
+	cancel   context.CancelFunc
+}
+
+// CanBindInputPin checks whether the pin can be bound to the pair of pairInputPin.
+func (s *Service) CanBindInputPin(pin, pairInputPin domain.Pin) error {
+	s.pinMux.Lock()
+	b, ok := s.bindings[pin]
+	_, paired := s.pinPairs[pairInputPin]
+	s.pinMux.Unlock()
+	if !paired {
+		return fmt.Errorf("Pin %v is not registered as input pin", pairInputPin)
+	}
+	if !ok {
+		return s.IsPinRegistered(pin)
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	if _, ok := b.targets[pairInputPin]; ok {
+		return fmt.Errorf("Pin %v is already bound to input pin %v", pin, pairInputPin)
+	}
+	return nil
+}
+
+// BindInputPin attaches an additional input to the pair of pairInputPin. Without OnGesture every change
+// of the input toggles the pair, or pulses a timed one on press. An input can be bound to any number of
+// pairs, its wiring, debounce and double press detection are taken from the first binding.
+func (s *Service) BindInputPin(pin, pairInputPin domain.Pin, opts PairOptions) error {
+	if !s.isActive() {
+		return inactiveErr
+	}
+	if err := s.CanBindInputPin(pin, pairInputPin); err != nil {
+		return err
+	}
+
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	if b, ok := s.bindings[pin]; ok {
+		b.targets[pairInputPin] = opts.OnGesture
+		b.owners[pairInputPin] = opts.Owner
+		return nil
+	}
+	wi, err := s.openPin(pin, enum.PinModeInput, opts.InputPull, opts.InputPolarity, enum.PinRoleBoundInput, opts.Owner)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	b := &binding{
+		targets: map[domain.Pin]func(enum.Gesture){pairInputPin: opts.OnGesture},
+		owners:  map[domain.Pin]string{pairInputPin: opts.Owner},
+		cancel:  cancel,
+	}
+	b.gestures = createGestureRecognizer(PairOptions{
+		OnGesture: func(g enum.Gesture) {
+			s.bindingGesture(b, g)
+		},
+		DoublePress: opts.DoublePress,
+	})
+	s.workers.Add(1)
+	go s.bindingWorker(ctx, s.watchInput(pin, wi, opts.Debounce), b)
+	s.bindings[pin] = b
+	return nil
+}
+
+// UnbindInputPin detaches the input from the pair of pairInputPin, the pin is released with its last pair.
+func (s *Service) UnbindInputPin(pin, pairInputPin domain.Pin) error {
+	if !s.isActive() {
+		return inactiveErr
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	b, ok := s.bindings[pin]
+	if !ok {
+		return fmt.Errorf("Pin %v is not registered as bound input pin", pin)
+	}
+	if _, ok := b.targets[pairInputPin]; !ok {
+		return fmt.Errorf("Pin %v is not bound to input pin %v", pin, pairInputPin)
+	}
+	delete(b.targets, pairInputPin)
+	delete(b.owners, pairInputPin)
+	if len(b.targets) == 0 {
+		return s.internalUnregisterBinding(pin)
+	}
+	return nil
+}
+
+func (s *Service) internalUnregisterBinding(pin domain.Pin) error {
+	b, ok := s.bindings[pin]
+	if !ok {
+		return fmt.Errorf("Pin %v is not registered as bound input pin", pin)
+	}
+	b.cancel()
+	b.gestures.stop()
+	delete(s.bindings, pin)
+	s.releasePins(pin)
+	return nil
+}
+
+// bindingWorker passes the changes of the input to the bound pairs. It is not waited for when unregistered,
+// as it may be waiting for pinMux itself. The pairs are looked up under pinMux, which is released
+// before the edge is sent to their workers.
+func (s *Service) bindingWorker(ctx context.Context, in *input, b *binding) {
+	defer s.workers.Done()
+	defer in.stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case v := <-in.events:
+			s.pinMux.Lock()
+			gestures := false
+			targets := make(map[domain.Pin]*pair, len(b.targets))
+			for target, handler := range b.targets {
+				if handler != nil {
+					gestures = true
+				} else if p, ok := s.pinPairs[target]; ok {
+					targets[target] = p
+				}
+			}
+			s.pinMux.Unlock()
+			for target, p := range targets {
+				if err := p.send(pairCommand{command: commandBoundEdge, state: v}); err != nil {
+					log.Println("Pin", target, "bound input error:", err)
+				}
+			}
+			if gestures {
+				b.gestures.edge(v)
+			}
+		}
+	}
+}
+
+func (s *Service) bindingGesture(b *binding, g enum.Gesture) {
+	var handlers []func(enum.Gesture)
+	s.pinMux.Lock()
+	for _, handler := range b.targets {
+		if handler != nil {
+			handlers = append(handlers, handler)
+		}
+	}
+	s.pinMux.Unlock()
+	for _, handler := range handlers {
+		handler(g)
+	}
+}
@@ -0,0 +1,158 @@
+package gpio
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/dto"
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+const (
+	// owner of the pins opened to check the wiring, as shown in the diagnostics
+	commissioningOwner = "Commissioning"
+	// a relay left pulsed by a forgotten request is released after it anyway
+	maxCommissioningPulse = time.Minute
+)
+
+// commissioningPulse keeps an unassigned output active until the time set, which a repeated pulse moves.
+type commissioningPulse struct {
+	timer *time.Timer
+	until time.Time
+}
+
+// PulsePin activates an output not claimed by any device for the duration, so its relay can be checked.
+// Pulsing the pin again keeps it active for the new duration.
+func (s *Service) PulsePin(pin domain.Pin, duration time.Duration, polarity enum.Polarity) error {
+	if !s.isActive() {
+		return inactiveErr
+	}
+	if duration <= 0 || duration > maxCommissioningPulse {
+		return fmt.Errorf("Pulse duration must be between 0 and %v", maxCommissioningPulse)
+	}
+	if !polarity.IsValid() {
+		return fmt.Errorf("Invalid polarity '%d'", polarity)
+	}
+	// the pin is registered once, the concurrent pulses extend it
+	s.pulseMux.Lock()
+	defer s.pulseMux.Unlock()
+	s.pinMux.Lock()
+	if p, ok := s.pulses[pin]; ok {
+		p.until = time.Now().Add(duration)
+		p.timer.Reset(duration)
+		s.pinMux.Unlock()
+		return nil
+	}
+	s.pinMux.Unlock()
+
+	// the relay of a device failing to register is still wired to the pin
+	if err := s.is.CheckUnclaimed(pin); err != nil {
+		return err
+	}
+	if err := s.RegisterOutputPin(pin, OutputOptions{Polarity: polarity, Owner: commissioningOwner}); err != nil {
+		return err
+	}
+	if err := s.SetOutputState(pin, true); err != nil {
+		s.UnregisterOutputPin(pin)
+		return err
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	s.pulses[pin] = &commissioningPulse{
+		timer: time.AfterFunc(duration, func() { s.endPulse(pin) }),
+		until: time.Now().Add(duration),
+	}
+	log.Printf("Pulsing pin %v for %v\n", pin, duration)
+	return nil
+}
+
+func (s *Service) endPulse(pin domain.Pin) {
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	p, ok := s.pulses[pin]
+	if !ok {
+		return
+	}
+	if remaining := time.Until(p.until); remaining > 0 {
+		p.timer.Reset(remaining)
+		return
+	}
+	delete(s.pulses, pin)
+	if err := s.internalUnregisterOutputPin(pin); err != nil {
+		log.Println("Pin", pin, "pulse end error:", err)
+	}
+}
+
+// WatchPin reports the raw level changes of an input not claimed by any device, until the context is done.
+// The channel receives all events of the pin, it is closed once the watch ends.
+func (s *Service) WatchPin(ctx context.Context, pin domain.Pin, pull enum.Pull) (<-chan Event, error) {
+	if !s.isActive() {
+		return nil, inactiveErr
+	}
+	if !pull.IsValid() {
+		return nil, fmt.Errorf("Invalid pull '%d'", pull)
+	}
+	if err := s.is.CheckUnclaimed(pin); err != nil {
+		return nil, err
+	}
+	// subscribed first, so the level read when the pin is opened is not missed
+	events, cancel := s.Subscribe(pin)
+	opts := InputOptions{Polarity: enum.PolarityActiveHigh, Pull: pull, Owner: commissioningOwner}
+	if err := s.RegisterInputPin(pin, opts, func(bool) {}); err != nil {
+		cancel()
+		return nil, err
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.ctx.Done():
+		}
+		cancel()
+		s.UnregisterInputPin(pin)
+	}()
+	return events, nil
+}
+
+// GetClaimedPins lists the pins in use, with the devices they are used by, and the pins claimed
+// by the devices which failed to register.
+func (s *Service) GetClaimedPins() ([]dto.GpioClaim, error) {
+	if !s.isActive() {
+		return nil, inactiveErr
+	}
+	allocations, err := s.is.Browse()
+	if err != nil {
+		return nil, err
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	ret := make([]dto.GpioClaim, 0, len(s.pins))
+	for pin, info := range s.pins {
+		ret = append(ret, dto.GpioClaim{
+			Pin:        pin,
+			Role:       info.role,
+			Owner:      info.owner,
+			Registered: true,
+		})
+	}
+	listed := make(map[domain.Pin]bool)
+	for _, a := range allocations {
+		// an input bound to several devices is listed once
+		if _, ok := s.pins[a.Pin]; ok || listed[a.Pin] {
+			continue
+		}
+		listed[a.Pin] = true
+		ret = append(ret, dto.GpioClaim{
+			Pin:   a.Pin,
+			Role:  a.Role,
+			Owner: fmt.Sprintf("%s '%s'", a.Device, a.Label),
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Pin.Less(ret[j].Pin)
+	})
+	return ret, nil
+}
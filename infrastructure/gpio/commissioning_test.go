@@ -0,0 +1,63 @@
+package gpio
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Erexo/Ventana/core/enum"
+	"github.com/Erexo/Ventana/infrastructure/db"
+)
+
+// useTestDatabase creates the database in a temporary directory, the inventory checks the pins against it.
+func useTestDatabase(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+	})
+	if err := db.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentPulsesShareThePin(t *testing.T) {
+	useTestDatabase(t)
+	d := CreateSimulatedDriver()
+	s := createTestService(t, d)
+	const pulses = 8
+	errs := make(chan error, pulses)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < pulses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- s.PulsePin(testOutputPin, 200*time.Millisecond, enum.PolarityActiveHigh)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForLevel(t, d, testOutputPin, true)
+	// the pulse ends once and releases the pin
+	waitForLevel(t, d, testOutputPin, false)
+	waitFor(t, "the pin to be released", func() bool { return s.IsPinRegistered(testOutputPin) == nil })
+	if err := s.PulsePin(testOutputPin, 50*time.Millisecond, enum.PolarityActiveHigh); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, d, testOutputPin, true)
+}
@@ -0,0 +1,139 @@
+package gpio
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Erexo/Ventana/api/controller"
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/dto"
+	"github.com/Erexo/Ventana/core/enum"
+	"github.com/go-chi/chi"
+)
+
+type Controller struct {
+	s *Service
+}
+
+func CreateController(s *Service) *Controller {
+	return &Controller{
+		s: s,
+	}
+}
+
+func (c *Controller) GetPrefix() string {
+	return "/gpio"
+}
+
+func (c *Controller) Route(r chi.Router) {
+	r.Use(controller.RequireRole(domain.RoleAdmin))
+	r.Get("/", c.diagnostics)
+	r.Get("/claims", c.claims)
+	r.Post("/pulse", c.pulse)
+	r.Get("/watch/{pin}", c.watch)
+}
+
+// @Router /api/gpio [get]
+// @Success 200 {object} dto.GpioDiagnostics
+// @Produce  json
+// @Security ApiKeyAuth
+func (c *Controller) diagnostics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+	ret, err := c.s.GetDiagnostics()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	retj, _ := json.Marshal(ret)
+	w.WriteHeader(http.StatusOK)
+	w.Write(retj)
+}
+
+// @Router /api/gpio/claims [get]
+// @Success 200 {array} dto.GpioClaim
+// @Produce  json
+// @Security ApiKeyAuth
+func (c *Controller) claims(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+	ret, err := c.s.GetClaimedPins()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	retj, _ := json.Marshal(ret)
+	w.WriteHeader(http.StatusOK)
+	w.Write(retj)
+}
+
+// @Router /api/gpio/pulse [post]
+// @Param body body pulseDto true "body"
+// @Success 200 {string} plain
+// @Accept  json
+// @Produce  plain
+// @Security ApiKeyAuth
+func (c *Controller) pulse(w http.ResponseWriter, r *http.Request) {
+	var d pulseDto
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.PulsePin(d.Pin, time.Duration(d.Seconds)*time.Second, d.Polarity); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// watch streams the level changes of the input as JSON lines, until the request is cancelled.
+// @Router /api/gpio/watch/{pin} [get]
+// @Param pin path string true "path"
+// @Param pull query int false "query"
+// @Success 200 {object} dto.GpioLevel
+// @Produce  json
+// @Security ApiKeyAuth
+func (c *Controller) watch(w http.ResponseWriter, r *http.Request) {
+	pin, err := domain.ParsePin(chi.URLParam(r, "pin"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pull := enum.PullUp
+	if v := r.URL.Query().Get("pull"); v != "" {
+		p, err := strconv.ParseUint(v, 10, 8)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pull = enum.Pull(p)
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, err := c.s.WatchPin(r.Context(), pin, pull)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("content-type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	enc := json.NewEncoder(w)
+	for e := range events {
+		if e.Type != enum.PinEventInput {
+			continue
+		}
+		if err := enc.Encode(dto.GpioLevel{Pin: e.Pin, Level: e.State, Time: e.Time}); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+type pulseDto struct {
+	Pin      domain.Pin    `json:"pin" swaggertype:"string"`
+	Seconds  int           `json:"seconds"`
+	Polarity enum.Polarity `json:"polarity"`
+}
@@ -0,0 +1,188 @@
+package gpio
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/dto"
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+// pinInfo keeps what the diagnostics report about a registered pin.
+type pinInfo struct {
+	pin      DriverPin
+	role     enum.PinRole
+	owner    string
+	polarity enum.Polarity
+
+	mux         sync.Mutex
+	state       bool
+	readErrors  uint64
+	writeErrors uint64
+	lastRead    time.Time
+}
+
+func (i *pinInfo) read(state bool, err error) {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	if err != nil {
+		i.readErrors++
+		return
+	}
+	i.state = state
+	i.lastRead = time.Now()
+}
+
+func (i *pinInfo) write(state bool, err error) {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	if err != nil {
+		i.writeErrors++
+		return
+	}
+	i.state = state
+}
+
+// monitoredPin records the reads and writes of a logical pin for the diagnostics.
+type monitoredPin struct {
+	DriverPin
+	info *pinInfo
+}
+
+func (p monitoredPin) ReadState() (bool, error) {
+	state, err := p.DriverPin.ReadState()
+	p.info.read(state, err)
+	return state, err
+}
+
+func (p monitoredPin) WriteState(state bool) error {
+	err := p.DriverPin.WriteState(state)
+	p.info.write(state, err)
+	return err
+}
+
+func (p monitoredPin) WriteDuty(duty uint8) error {
+	err := p.DriverPin.(PwmPin).WriteDuty(duty)
+	p.info.write(duty > 0, err)
+	return err
+}
+
+func (p monitoredPin) Watch(handler func(state bool)) (func(), error) {
+	ep, ok := p.DriverPin.(EdgePin)
+	if !ok {
+		return nil, fmt.Errorf("Pin %v does not report edges", p.DriverPin)
+	}
+	return ep.Watch(func(state bool) {
+		// an edge is reported by a successful read of the driver
+		p.info.read(state, nil)
+		handler(state)
+	})
+}
+
+// openPin opens the pin through the driver, the returned pin works with logical states and is monitored.
+// An output is switched to its inactive level right away, the drivers leave it at the power-on level of the chip,
+// which is high on a PCF expander and low on the others. It has to be called under pinMux.
+func (s *Service) openPin(pin domain.Pin, mode enum.PinMode, pull enum.Pull, polarity enum.Polarity, role enum.PinRole, owner string) (DriverPin, error) {
+	dp, err := s.driver.OpenPin(pin, mode, pull)
+	if err != nil {
+		return nil, err
+	}
+	if mode == enum.PinModeOutput {
+		if err := (logicalPin{dp, polarity}).WriteState(defaultPinState); err != nil {
+			return nil, fmt.Errorf("Pin %v inactive write error: %w", pin, err)
+		}
+	}
+	info := &pinInfo{
+		pin:      dp,
+		role:     role,
+		owner:    owner,
+		polarity: polarity,
+		state:    defaultPinState,
+	}
+	s.pins[pin] = info
+	return monitoredPin{logicalPin{dp, polarity}, info}, nil
+}
+
+// releasePins forgets the pins in the diagnostics, it has to be called under pinMux.
+func (s *Service) releasePins(pins ...domain.Pin) {
+	for _, p := range pins {
+		delete(s.pins, p)
+	}
+}
+
+func (s *Service) GetDiagnostics() (dto.GpioDiagnostics, error) {
+	if !s.isActive() {
+		return dto.GpioDiagnostics{}, inactiveErr
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	ret := dto.GpioDiagnostics{
+		Pins:      make([]dto.GpioPin, 0, len(s.pins)),
+		Expanders: []dto.GpioExpander{},
+	}
+	for pin, info := range s.pins {
+		owner := info.owner
+		if b, ok := s.bindings[pin]; ok {
+			owner = b.getOwner()
+		}
+		info.mux.Lock()
+		p := dto.GpioPin{
+			Pin:         pin,
+			Role:        info.role,
+			Owner:       owner,
+			State:       info.state,
+			Level:       info.polarity.GetLevel(info.state),
+			ReadErrors:  info.readErrors,
+			WriteErrors: info.writeErrors,
+			Degraded:    isDegraded(info.pin),
+		}
+		if !info.lastRead.IsZero() {
+			lastRead := info.lastRead
+			p.LastRead = &lastRead
+		}
+		info.mux.Unlock()
+		ret.Pins = append(ret.Pins, p)
+	}
+	sort.Slice(ret.Pins, func(i, j int) bool {
+		return ret.Pins[i].Pin.Less(ret.Pins[j].Pin)
+	})
+	if ed, ok := s.driver.(ExpanderDriver); ok {
+		ret.Expanders = ed.GetExpanders()
+	}
+	return ret, nil
+}
+
+// IsDegraded tells whether any of the pins belongs to a failing device.
+func (s *Service) IsDegraded(pins ...domain.Pin) bool {
+	if !s.isActive() {
+		return false
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	for _, p := range pins {
+		if info, ok := s.pins[p]; ok && isDegraded(info.pin) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDegraded(pin DriverPin) bool {
+	fp, ok := pin.(FaultPin)
+	return ok && fp.IsDegraded()
+}
+
+func (b *binding) getOwner() string {
+	owners := make([]string, 0, len(b.owners))
+	for _, owner := range b.owners {
+		if owner != "" {
+			owners = append(owners, owner)
+		}
+	}
+	sort.Strings(owners)
+	return strings.Join(owners, ", ")
+}
@@ -0,0 +1,318 @@
+package gpio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+const (
+	// step of the fades and of the dimming by a held input
+	fadeInterval = 20 * time.Millisecond
+	// how long a held input takes to dim through the whole range
+	holdDimTime = 4 * time.Second
+	// a held input does not dim a light below it, so it can not be switched off that way
+	minDimLevel = 1
+	// period of the software PWM, frequent enough for a steady light
+	softPwmPeriod = 10 * time.Millisecond
+)
+
+// SetPinLevel sets the brightness of a dimmable pair in percent, 0 switches it off keeping the brightness.
+func (s *Service) SetPinLevel(inputPin domain.Pin, level uint8) error {
+	if !s.isActive() {
+		return inactiveErr
+	}
+	if level > maxDuty {
+		return fmt.Errorf("Level must be between 0 and %d", maxDuty)
+	}
+	s.pinMux.Lock()
+	p, ok := s.pinPairs[inputPin]
+	s.pinMux.Unlock()
+	if !ok {
+		return errors.New(fmt.Sprintf("Pin %v is not registered as input pin", inputPin))
+	}
+	if p.pairType != enum.PairTypeDimmable {
+		return fmt.Errorf("Pin %v is not registered as a dimmable pair", inputPin)
+	}
+	return p.send(pairCommand{command: commandLevel, level: level})
+}
+
+// GetPinLevel returns the brightness a pair has when on, which is full for the pairs that are not dimmable.
+func (s *Service) GetPinLevel(inputPin domain.Pin) uint8 {
+	if !s.isActive() {
+		return 0
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	p, ok := s.pinPairs[inputPin]
+	if !ok {
+		return 0
+	}
+	if p.pairType != enum.PairTypeDimmable {
+		return maxDuty
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.level
+}
+
+// openDimmer opens the output in the hardware PWM mode, or as a plain output driven by a software PWM
+// on the native pins without it, the expander pins are refused by RegisterPinPair.
+// The returned function stops the software PWM once the worker is done with the output.
+func (s *Service) openDimmer(pin domain.Pin, polarity enum.Polarity, owner string) (PwmPin, func(), error) {
+	wo, err := s.openPin(pin, enum.PinModePwm, enum.PullNone, polarity, enum.PinRoleOutput, owner)
+	if err == nil {
+		return wo.(PwmPin), func() {}, nil
+	}
+	if !errors.Is(err, ErrNoPwm) {
+		return nil, nil, err
+	}
+	wo, err = s.openPin(pin, enum.PinModeOutput, enum.PullNone, polarity, enum.PinRoleOutput, owner)
+	if err != nil {
+		return nil, nil, err
+	}
+	mp := wo.(monitoredPin)
+	pwm := startSoftPwm(mp.DriverPin, mp.info)
+	return pwm, pwm.stop, nil
+}
+
+// dimmablePairWorker fades the output to the brightness of the pair. A short press of the input toggles it,
+// holding the input dims it up or down, the direction changing with every hold.
+func (s *Service) dimmablePairWorker(ctx context.Context, in *input, out PwmPin, closeOut func(), p *pair, desired bool) {
+	defer s.workers.Done()
+	defer close(p.done)
+	defer in.stop()
+	defer closeOut()
+	ticker := time.NewTicker(fadeInterval)
+	defer ticker.Stop()
+	ticking := true
+	level := p.level
+	var current, dimLevel float64
+	var pressedAt time.Time
+	written := -1
+	inputState, holding, dimUp := defaultPinState, false, true
+	for {
+		select {
+		case <-ctx.Done():
+			if err := out.WriteDuty(0); err != nil {
+				log.Println("Pin", p.outputPin, "inactive write error:", err)
+				s.events.error(p.outputPin, err)
+			}
+			return
+		case c := <-p.commands:
+			switch c.command {
+			case commandToggle:
+				desired = !desired
+			case commandSet:
+				desired = c.state
+			case commandBoundEdge:
+				// push buttons, as the input of the pair
+				if !c.state {
+					desired = !desired
+				}
+			case commandLevel:
+				desired = c.level > 0
+				if c.level > 0 && c.level != level {
+					level = c.level
+					p.setLevel(level)
+					s.reportLevel(p, level)
+				}
+			}
+		case v := <-in.events:
+			if p.gestures != nil {
+				p.gestures.edge(v)
+			}
+			if v != inputState {
+				inputState = v
+				if v {
+					pressedAt = time.Now()
+				} else {
+					if holding {
+						holding = false
+						dimUp = !dimUp
+						s.reportLevel(p, level)
+					} else if p.gestures == nil {
+						desired = !desired
+					}
+					pressedAt = time.Time{}
+				}
+			}
+		case <-ticker.C:
+		}
+		if p.holdToDim && !pressedAt.IsZero() && time.Since(pressedAt) >= longPressTime {
+			if !holding {
+				holding = true
+				dimLevel = float64(level)
+				if !desired {
+					// a light held while off comes on at the lowest level
+					desired = true
+					dimLevel, dimUp = minDimLevel, true
+				}
+			}
+			step := maxDuty * float64(fadeInterval) / float64(holdDimTime)
+			if !dimUp {
+				step = -step
+			}
+			dimLevel = math.Max(minDimLevel, math.Min(maxDuty, dimLevel+step))
+			if v := uint8(math.Round(dimLevel)); v != level {
+				level = v
+				p.setLevel(level)
+			}
+		}
+
+		target := 0.0
+		if desired {
+			target = float64(level)
+		}
+		if holding || p.fadeDuration <= 0 {
+			current = target
+		} else {
+			step := maxDuty * float64(fadeInterval) / float64(p.fadeDuration)
+			if current < target {
+				current = math.Min(target, current+step)
+			} else {
+				current = math.Max(target, current-step)
+			}
+		}
+		duty := int(math.Round(current))
+		if duty != written {
+			if err := out.WriteDuty(uint8(duty)); err != nil {
+				log.Println("Pin", p.outputPin, "write error:", err)
+				s.events.error(p.outputPin, err)
+			} else {
+				written = duty
+				s.setOutputState(p, duty > 0)
+			}
+		}
+		// the ticker runs while fading, while the input is held and to retry a failed write
+		busy := current != target || duty != written || (p.holdToDim && !pressedAt.IsZero())
+		if busy != ticking {
+			ticking = busy
+			if busy {
+				ticker.Reset(fadeInterval)
+			} else {
+				ticker.Stop()
+			}
+		}
+	}
+}
+
+// setLevel records the brightness of the pair, it is called by the worker of the pair only.
+func (p *pair) setLevel(level uint8) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.level = level
+}
+
+// reportLevel announces the brightness set, it is called by the worker of the pair only.
+func (s *Service) reportLevel(p *pair, level uint8) {
+	s.events.level(p.outputPin, level)
+	if p.onLevel != nil {
+		p.onLevel(level)
+	}
+}
+
+// softPwm drives a plain output in the duty cycle set. The diagnostics show the pin on for a duty cycle
+// above 0 rather than the levels of every period, the failed writes are counted all the same.
+type softPwm struct {
+	pin     DriverPin
+	info    *pinInfo
+	duty    uint32
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func startSoftPwm(pin DriverPin, info *pinInfo) *softPwm {
+	p := &softPwm{
+		pin:     pin,
+		info:    info,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go p.worker()
+	return p
+}
+
+func (p *softPwm) WriteDuty(duty uint8) error {
+	atomic.StoreUint32(&p.duty, uint32(duty))
+	p.info.write(duty > 0, nil)
+	return nil
+}
+
+func (p *softPwm) worker() {
+	defer close(p.stopped)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	wait := func(d time.Duration) bool {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(d)
+		select {
+		case <-p.done:
+			return false
+		case <-timer.C:
+			return true
+		}
+	}
+	var level, known, failing bool
+	set := func(state bool) {
+		if known && level == state {
+			return
+		}
+		err := p.pin.WriteState(state)
+		if err == nil {
+			level, known = state, true
+		} else {
+			p.info.write(state, err)
+		}
+		// a failing pin would flood the log once per period
+		if (err != nil) != failing {
+			failing = err != nil
+			if failing {
+				log.Println("Pin", p.pin, "software PWM write error:", err)
+			}
+		}
+	}
+	for {
+		duty := time.Duration(atomic.LoadUint32(&p.duty))
+		if duty == 0 || duty >= maxDuty {
+			set(duty > 0)
+			if !wait(softPwmPeriod) {
+				return
+			}
+			continue
+		}
+		on := softPwmPeriod * duty / maxDuty
+		set(true)
+		if !wait(on) {
+			return
+		}
+		set(false)
+		if !wait(softPwmPeriod - on) {
+			return
+		}
+	}
+}
+
+// stop ends the PWM, leaving the output fully on or off by the last duty cycle.
+func (p *softPwm) stop() {
+	close(p.done)
+	<-p.stopped
+	state := atomic.LoadUint32(&p.duty) >= maxDuty/2
+	err := p.pin.WriteState(state)
+	p.info.write(state, err)
+	if err != nil {
+		log.Println("Pin", p.pin, "write error:", err)
+	}
+}
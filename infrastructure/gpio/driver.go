@@ -0,0 +1,149 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/dto"
+	"github.com/Erexo/Ventana/core/enum"
+	"github.com/Erexo/Ventana/core/utils"
+	"github.com/Erexo/Ventana/infrastructure/config"
+)
+
+const (
+	DriverHardware  = "hardware"
+	DriverSimulated = "simulated"
+
+	// the duty cycle is set in percent
+	maxDuty = 100
+)
+
+// ErrNoPwm is returned by OpenPin in PinModePwm for the pins without a hardware PWM.
+var ErrNoPwm = errors.New("Pin has no hardware PWM")
+
+// Driver gives the Service access to the pins of a hardware backend.
+type Driver interface {
+	// OpenPin configures the pin in the given mode and returns a handle used by the workers,
+	// the pull resistor is applied to input pins only.
+	OpenPin(pin domain.Pin, mode enum.PinMode, pull enum.Pull) (DriverPin, error)
+	Close() error
+}
+
+type DriverPin interface {
+	ReadState() (bool, error)
+	WriteState(state bool) error
+	fmt.Stringer
+}
+
+// EdgePin is implemented by input pins able to report level changes without being polled.
+type EdgePin interface {
+	// Watch calls the handler with the new level of the pin whenever it changes, until stop is called.
+	Watch(handler func(state bool)) (stop func(), err error)
+}
+
+// PwmPin is implemented by output pins opened in PinModePwm.
+type PwmPin interface {
+	// WriteDuty sets the share of the period the pin is high, in percent.
+	WriteDuty(duty uint8) error
+}
+
+// FaultPin is implemented by pins of devices which can fail and recover as a whole.
+type FaultPin interface {
+	// IsDegraded tells whether the device of the pin is failing and being recovered.
+	IsDegraded() bool
+}
+
+// ExpanderDriver is implemented by the drivers talking to IO expanders.
+type ExpanderDriver interface {
+	// GetExpanders lists the opened expanders, it is called under the Service lock like OpenPin.
+	GetExpanders() []dto.GpioExpander
+}
+
+func CreateDriver(cfg config.Configuration) (Driver, error) {
+	switch cfg.GpioDriver {
+	case "", DriverHardware:
+		if err := ValidateExpanders(cfg.Expanders); err != nil {
+			return nil, err
+		}
+		return createHardwareDriver(cfg.Expanders), nil
+	case DriverSimulated:
+		return CreateSimulatedDriver(), nil
+	default:
+		return nil, fmt.Errorf("Unknown gpio driver '%s'", cfg.GpioDriver)
+	}
+}
+
+// hardwareDriver routes native pins to rpio and expander pins to the I2C expanders.
+type hardwareDriver struct {
+	rpio      *rpioDriver
+	expanders *expanderDriver
+}
+
+func createHardwareDriver(expanders []config.ExpanderConfig) *hardwareDriver {
+	rpio := createRpioDriver()
+	return &hardwareDriver{
+		rpio:      rpio,
+		expanders: createExpanderDriver(rpio, expanders),
+	}
+}
+
+func (d *hardwareDriver) OpenPin(pin domain.Pin, mode enum.PinMode, pull enum.Pull) (DriverPin, error) {
+	switch pin.Driver {
+	case domain.PinDriverRpio:
+		return d.rpio.OpenPin(pin, mode, pull)
+	case domain.PinDriverMcp, domain.PinDriverPcf:
+		return d.expanders.OpenPin(pin, mode, pull)
+	default:
+		return nil, fmt.Errorf("Invalid pin '%v'", pin)
+	}
+}
+
+func (d *hardwareDriver) GetExpanders() []dto.GpioExpander {
+	return d.expanders.GetExpanders()
+}
+
+func (d *hardwareDriver) Close() error {
+	// expanders first, their interrupt lines are watched through rpio
+	err := d.expanders.Close()
+	return utils.ConcatErrors(err, d.rpio.Close())
+}
+
+// logicalPin translates the logical on/off state used by the Service to the level of the wiring.
+type logicalPin struct {
+	DriverPin
+	polarity enum.Polarity
+}
+
+func (p logicalPin) ReadState() (bool, error) {
+	level, err := p.DriverPin.ReadState()
+	if err != nil {
+		return false, err
+	}
+	return p.polarity.IsActive(level), nil
+}
+
+func (p logicalPin) WriteState(active bool) error {
+	return p.DriverPin.WriteState(p.polarity.GetLevel(active))
+}
+
+func (p logicalPin) WriteDuty(duty uint8) error {
+	pp, ok := p.DriverPin.(PwmPin)
+	if !ok {
+		return fmt.Errorf("%v: %w", p.DriverPin, ErrNoPwm)
+	}
+	if !p.polarity.GetLevel(true) {
+		duty = maxDuty - duty
+	}
+	return pp.WriteDuty(duty)
+}
+
+func (p logicalPin) Watch(handler func(active bool)) (func(), error) {
+	ep, ok := p.DriverPin.(EdgePin)
+	if !ok {
+		return nil, fmt.Errorf("Pin %v does not report edges", p.DriverPin)
+	}
+	return ep.Watch(func(level bool) {
+		handler(p.polarity.IsActive(level))
+	})
+}
@@ -0,0 +1,122 @@
+package gpio
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+// events a subscriber may lag behind before the following ones are dropped
+const eventBufferSize = 64
+
+// Event is published by the pin workers, State is set for input and output events,
+// Level for level events and Err for errors.
+type Event struct {
+	Type  enum.PinEvent
+	Pin   domain.Pin
+	State bool
+	Level uint8
+	Err   error
+	Time  time.Time
+}
+
+type subscriber struct {
+	events  chan Event
+	pins    map[domain.Pin]bool
+	dropped uint64
+}
+
+// eventBus fans the events out to the subscribers without ever waiting for them.
+type eventBus struct {
+	subscribers map[*subscriber]struct{}
+	closed      bool
+	mux         sync.RWMutex
+}
+
+func createEventBus() *eventBus {
+	return &eventBus{
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the events of the given pins, or of all pins when none are given.
+// The channel is closed by the returned cancel function or when the service is closed.
+func (s *Service) Subscribe(pins ...domain.Pin) (<-chan Event, func()) {
+	return s.events.subscribe(pins)
+}
+
+func (b *eventBus) subscribe(pins []domain.Pin) (<-chan Event, func()) {
+	sub := &subscriber{
+		events: make(chan Event, eventBufferSize),
+	}
+	if len(pins) > 0 {
+		sub.pins = make(map[domain.Pin]bool)
+		for _, p := range pins {
+			sub.pins[p] = true
+		}
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.closed {
+		close(sub.events)
+		return sub.events, func() {}
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub.events, func() {
+		b.mux.Lock()
+		defer b.mux.Unlock()
+		if _, ok := b.subscribers[sub]; ok {
+			delete(b.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+func (b *eventBus) publish(e Event) {
+	e.Time = time.Now()
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	for sub := range b.subscribers {
+		if sub.pins != nil && !sub.pins[e.Pin] {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			// a slow subscriber must not hold up the pin workers
+			if atomic.AddUint64(&sub.dropped, 1) == 1 {
+				log.Println("Pin event subscriber is lagging, dropping events")
+			}
+		}
+	}
+}
+
+func (b *eventBus) input(pin domain.Pin, state bool) {
+	b.publish(Event{Type: enum.PinEventInput, Pin: pin, State: state})
+}
+
+func (b *eventBus) output(pin domain.Pin, state bool) {
+	b.publish(Event{Type: enum.PinEventOutput, Pin: pin, State: state})
+}
+
+func (b *eventBus) level(pin domain.Pin, level uint8) {
+	b.publish(Event{Type: enum.PinEventLevel, Pin: pin, Level: level})
+}
+
+func (b *eventBus) error(pin domain.Pin, err error) {
+	b.publish(Event{Type: enum.PinEventError, Pin: pin, Err: err})
+}
+
+func (b *eventBus) close() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	for sub := range b.subscribers {
+		close(sub.events)
+	}
+	b.subscribers = make(map[*subscriber]struct{})
+	b.closed = true
+}
@@ -0,0 +1,560 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/dto"
+	"github.com/Erexo/Ventana/core/enum"
+	"github.com/Erexo/Ventana/core/utils"
+	"github.com/Erexo/Ventana/infrastructure/config"
+	"github.com/stianeikeland/go-rpio"
+)
+
+const (
+	ExpanderMcp23017 = "mcp23017"
+	ExpanderMcp23008 = "mcp23008"
+	ExpanderPcf8574  = "pcf8574"
+	ExpanderPcf8575  = "pcf8575"
+
+	// safety net for interrupts lost on the wire, the read also clears a stuck INT line
+	expanderResyncInterval = time.Second
+
+	// consecutive failed transfers after which the chip is considered faulty and reopened
+	expanderFaultThreshold     = 5
+	expanderRecoveryMinBackoff = time.Second
+	expanderRecoveryMaxBackoff = time.Minute
+)
+
+var (
+	noInterruptErr = errors.New("Expander has no interrupt pin configured")
+	pullDownErr    = errors.New("Expander has no pull-down resistors")
+	// ErrDegraded is returned for the pins of a faulty expander until it is recovered.
+	ErrDegraded = errors.New("Expander is degraded")
+)
+
+// ValidateExpanders checks the chip inventory of the configuration.
+func ValidateExpanders(expanders []config.ExpanderConfig) error {
+	keys := make(map[expanderKey]bool)
+	for _, e := range expanders {
+		if e.Bus < 0 || e.Bus > 0xFF {
+			return fmt.Errorf("Invalid bus %d of expander", e.Bus)
+		}
+		if e.Address < 0x03 || e.Address > 0x77 {
+			return fmt.Errorf("Invalid address 0x%02x of expander on bus %d", e.Address, e.Bus)
+		}
+		// the pins address the chips by bus and address only, so the multiplexer channels can not share them
+		key := expanderKey{uint8(e.Bus), uint8(e.Address)}
+		if keys[key] {
+			return fmt.Errorf("Expander %v is configured twice", key)
+		}
+		keys[key] = true
+		if _, err := getExpanderPins(e.Type); err != nil {
+			return err
+		}
+		if e.MuxAddress != 0 && (e.MuxAddress < 0x03 || e.MuxAddress > 0x77 || e.MuxChannel > 7) {
+			return fmt.Errorf("Invalid multiplexer of expander %v", key)
+		}
+		if e.InterruptPin.Valid && (e.InterruptPin.Int64 < 0 || e.InterruptPin.Int64 > 0xFF) {
+			return fmt.Errorf("Invalid interrupt pin of expander %v", key)
+		}
+	}
+	return nil
+}
+
+// getExpanderDriver returns the pin driver name of the chip type, used in the pin addresses.
+func getExpanderDriver(expanderType string) string {
+	switch expanderType {
+	case ExpanderPcf8574, ExpanderPcf8575:
+		return domain.PinDriverPcf
+	default:
+		return domain.PinDriverMcp
+	}
+}
+
+func getExpanderPins(expanderType string) (uint8, error) {
+	switch expanderType {
+	case ExpanderMcp23017, ExpanderPcf8575:
+		return 16, nil
+	case ExpanderMcp23008, ExpanderPcf8574:
+		return 8, nil
+	default:
+		return 0, fmt.Errorf("Unknown expander type '%s'", expanderType)
+	}
+}
+
+// expanderKey identifies a chip, as addressed by its pins.
+type expanderKey struct {
+	bus     uint8
+	address uint8
+}
+
+func (k expanderKey) String() string {
+	return fmt.Sprintf("%d:0x%02x", k.bus, k.address)
+}
+
+type expanderDriver struct {
+	openedChips map[expanderKey]*expanderChip
+	buses       map[int]*i2cBus
+	// the buses are shared with the recovery of the chips
+	busMux    sync.Mutex
+	rpio      *rpioDriver
+	expanders map[expanderKey]config.ExpanderConfig
+}
+
+func createExpanderDriver(rpio *rpioDriver, expanders []config.ExpanderConfig) *expanderDriver {
+	d := &expanderDriver{
+		openedChips: make(map[expanderKey]*expanderChip),
+		buses:       make(map[int]*i2cBus),
+		rpio:        rpio,
+		expanders:   make(map[expanderKey]config.ExpanderConfig),
+	}
+	for _, e := range expanders {
+		d.expanders[expanderKey{uint8(e.Bus), uint8(e.Address)}] = e
+	}
+	return d
+}
+
+// getConfig finds the chip of the pin, the MCP23017s do not have to be configured.
+func (d *expanderDriver) getConfig(pin domain.Pin) (config.ExpanderConfig, error) {
+	key := expanderKey{pin.Bus, pin.Chip}
+	cfg, ok := d.expanders[key]
+	if !ok {
+		if pin.Driver != domain.PinDriverMcp {
+			return cfg, fmt.Errorf("Expander %s:%v is not configured", pin.Driver, key)
+		}
+		cfg = config.ExpanderConfig{
+			Type:    ExpanderMcp23017,
+			Bus:     int(pin.Bus),
+			Address: int(pin.Chip),
+		}
+	}
+	if driver := getExpanderDriver(cfg.Type); driver != pin.Driver {
+		return cfg, fmt.Errorf("Expander %v is configured as %s, not %s", key, driver, pin.Driver)
+	}
+	return cfg, nil
+}
+
+func (d *expanderDriver) openDevice(cfg config.ExpanderConfig) (expanderDevice, error) {
+	d.busMux.Lock()
+	bus, ok := d.buses[cfg.Bus]
+	if !ok {
+		bus = createI2cBus(cfg.Bus)
+		d.buses[cfg.Bus] = bus
+	}
+	d.busMux.Unlock()
+	t, err := openI2cTarget(bus, cfg.Address, cfg.MuxAddress, cfg.MuxChannel)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Type {
+	case ExpanderMcp23017:
+		return &mcpDevice{t: t, regs: mcp23017Registers, pins: 16}, nil
+	case ExpanderMcp23008:
+		return &mcpDevice{t: t, regs: mcp23008Registers, pins: 8}, nil
+	case ExpanderPcf8574:
+		return createPcfDevice(t, 8), nil
+	case ExpanderPcf8575:
+		return createPcfDevice(t, 16), nil
+	default:
+		t.close()
+		return nil, fmt.Errorf("Unknown expander type '%s'", cfg.Type)
+	}
+}
+
+func (d *expanderDriver) OpenPin(pin domain.Pin, mode enum.PinMode, pull enum.Pull) (DriverPin, error) {
+	if mode == enum.PinModePwm {
+		return nil, ErrNoPwm
+	}
+	if mode == enum.PinModeInput && pull == enum.PullDown {
+		return nil, pullDownErr
+	}
+	key := expanderKey{pin.Bus, pin.Chip}
+	chip, ok := d.openedChips[key]
+	if ok && chip.driver != pin.Driver {
+		return nil, fmt.Errorf("Expander %v is opened as %s, not %s", key, chip.driver, pin.Driver)
+	}
+	if !ok {
+		cfg, err := d.getConfig(pin)
+		if err != nil {
+			return nil, err
+		}
+		dev, err := d.openDevice(cfg)
+		if err != nil {
+			return nil, err
+		}
+		chip = &expanderChip{
+			key:      key,
+			driver:   pin.Driver,
+			cfg:      cfg,
+			dev:      dev,
+			expander: d,
+			pins:     make(map[uint8]expanderPinConfig),
+			watchers: make(map[uint8]func(state bool)),
+			closed:   make(chan struct{}),
+		}
+		d.openedChips[key] = chip
+	}
+	index := pin.Index
+	if index >= chip.dev.getPins() {
+		return nil, fmt.Errorf("Expander %v has only %d pins", chip, chip.dev.getPins())
+	}
+	chip.mux.Lock()
+	defer chip.mux.Unlock()
+	if chip.degraded {
+		return nil, ErrDegraded
+	}
+	cfg := expanderPinConfig{mode, pull}
+	if err := chip.result(chip.dev.configure(index, mode, pull)); err != nil {
+		return nil, err
+	}
+	chip.pins[index] = cfg
+	return expanderPin{
+		pinIndex: index,
+		chip:     chip,
+	}, nil
+}
+
+func (d *expanderDriver) GetExpanders() []dto.GpioExpander {
+	ret := make([]dto.GpioExpander, 0, len(d.openedChips))
+	for _, chip := range d.openedChips {
+		chip.watcherMux.Lock()
+		chip.mux.Lock()
+		ret = append(ret, dto.GpioExpander{
+			Driver:     chip.driver,
+			Type:       chip.cfg.Type,
+			Bus:        chip.cfg.Bus,
+			Address:    chip.cfg.Address,
+			MuxAddress: chip.cfg.MuxAddress,
+			MuxChannel: chip.cfg.MuxChannel,
+			Interrupts: chip.done != nil,
+			Degraded:   chip.degraded,
+		})
+		chip.mux.Unlock()
+		chip.watcherMux.Unlock()
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Bus != ret[j].Bus {
+			return ret[i].Bus < ret[j].Bus
+		}
+		return ret[i].Address < ret[j].Address
+	})
+	return ret
+}
+
+func (d *expanderDriver) Close() error {
+	var ret error
+	for _, chip := range d.openedChips {
+		if err := chip.close(); err != nil {
+			ret = utils.ConcatErrors(ret, fmt.Errorf("Expander %v: %w", chip, err))
+		}
+	}
+	d.busMux.Lock()
+	for _, bus := range d.buses {
+		ret = utils.ConcatErrors(ret, bus.close())
+	}
+	d.buses = make(map[int]*i2cBus)
+	d.busMux.Unlock()
+	d.openedChips = make(map[expanderKey]*expanderChip)
+	return ret
+}
+
+type expanderPinConfig struct {
+	mode enum.PinMode
+	pull enum.Pull
+}
+
+// expanderChip serializes the access to a single expander, shared by the pin workers and the interrupt worker.
+type expanderChip struct {
+	key      expanderKey
+	driver   string
+	cfg      config.ExpanderConfig
+	dev      expanderDevice
+	expander *expanderDriver
+	mux      sync.Mutex
+
+	// what is applied again once a faulty chip is reopened
+	pins          map[uint8]expanderPinConfig
+	outputs       uint16
+	interruptMask uint16
+	interrupts    bool
+	failures      int
+	degraded      bool
+	closed        chan struct{}
+
+	// interrupt handling, set up with the first watched pin
+	watchers   map[uint8]func(state bool)
+	levels     uint16
+	interrupt  chan struct{}
+	done       chan struct{}
+	stopIrq    func()
+	watcherMux sync.Mutex
+}
+
+func (c *expanderChip) String() string {
+	return fmt.Sprintf("%s:%v", c.driver, c.key)
+}
+
+func (c *expanderChip) watch(index uint8, handler func(state bool)) (func(), error) {
+	c.watcherMux.Lock()
+	defer c.watcherMux.Unlock()
+	if c.done == nil {
+		if err := c.startInterrupts(); err != nil {
+			return nil, err
+		}
+	}
+	c.mux.Lock()
+	err := c.dev.enableInterrupt(index, true)
+	if err == nil {
+		c.interruptMask |= 1 << index
+	}
+	c.mux.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	c.watchers[index] = handler
+	return func() {
+		c.watcherMux.Lock()
+		defer c.watcherMux.Unlock()
+		if _, ok := c.watchers[index]; !ok {
+			return
+		}
+		delete(c.watchers, index)
+		c.mux.Lock()
+		defer c.mux.Unlock()
+		c.interruptMask &^= 1 << index
+		if !c.degraded {
+			if err := c.dev.enableInterrupt(index, false); err != nil {
+				log.Printf("Expander %v interrupt disable error: %v\n", c, err)
+			}
+		}
+	}, nil
+}
+
+func (c *expanderChip) startInterrupts() error {
+	if !c.cfg.InterruptPin.Valid {
+		return noInterruptErr
+	}
+	c.mux.Lock()
+	err := c.dev.setupInterrupts()
+	if err == nil {
+		c.levels, err = c.dev.read()
+	}
+	if err == nil {
+		c.interrupts = true
+	}
+	c.mux.Unlock()
+	if err != nil {
+		return err
+	}
+	irq, err := c.expander.rpio.openIndex(uint8(c.cfg.InterruptPin.Int64), enum.PinModeInput, enum.PullUp)
+	if err != nil {
+		return err
+	}
+	c.interrupt = make(chan struct{}, 1)
+	c.done = make(chan struct{})
+	// INT is active low and stays asserted until the inputs are read
+	c.stopIrq = c.expander.rpio.watch(irq.pin, rpio.FallEdge, func(bool) {
+		select {
+		case c.interrupt <- struct{}{}:
+		default:
+		}
+	})
+	go c.interruptWorker(c.interrupt, c.done)
+	return nil
+}
+
+func (c *expanderChip) interruptWorker(interrupt, done chan struct{}) {
+	ticker := time.NewTicker(expanderResyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-interrupt:
+		case <-ticker.C:
+		}
+		c.readAll()
+	}
+}
+
+// readAll reads every pin in a single transfer and notifies the watchers of the changed pins.
+func (c *expanderChip) readAll() {
+	c.mux.Lock()
+	if c.degraded {
+		c.mux.Unlock()
+		return
+	}
+	levels, err := c.dev.read()
+	err = c.result(err)
+	c.mux.Unlock()
+	if err != nil {
+		log.Printf("Expander %v read error: %v\n", c, err)
+		return
+	}
+	changed := levels ^ c.levels
+	c.levels = levels
+	if changed == 0 {
+		return
+	}
+	c.watcherMux.Lock()
+	var handlers []func()
+	for index, handler := range c.watchers {
+		mask := uint16(1) << index
+		if changed&mask != 0 {
+			h, state := handler, levels&mask != 0
+			handlers = append(handlers, func() { h(state) })
+		}
+	}
+	c.watcherMux.Unlock()
+	for _, h := range handlers {
+		h()
+	}
+}
+
+// result counts the consecutive failed transfers, the chip is degraded and recovered in the background
+// once they reach expanderFaultThreshold. It has to be called under mux.
+func (c *expanderChip) result(err error) error {
+	if err == nil {
+		c.failures = 0
+		return nil
+	}
+	c.failures++
+	if c.failures >= expanderFaultThreshold && !c.degraded {
+		log.Printf("Expander %v failed %d times in a row, reopening\n", c, c.failures)
+		c.degraded = true
+		go c.recover()
+	}
+	return err
+}
+
+func (c *expanderChip) recover() {
+	backoff := expanderRecoveryMinBackoff
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-time.After(backoff):
+		}
+		if err := c.reopen(); err != nil {
+			log.Printf("Expander %v recovery error: %v\n", c, err)
+			if backoff *= 2; backoff > expanderRecoveryMaxBackoff {
+				backoff = expanderRecoveryMaxBackoff
+			}
+			continue
+		}
+		log.Printf("Expander %v recovered\n", c)
+		return
+	}
+}
+
+// reopen opens the chip again and restores the pin configuration, the output levels and the interrupts.
+func (c *expanderChip) reopen() error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	select {
+	case <-c.closed:
+		return nil
+	default:
+	}
+	c.dev.close()
+	dev, err := c.expander.openDevice(c.cfg)
+	if err != nil {
+		return err
+	}
+	c.dev = dev
+	for index, cfg := range c.pins {
+		if err := c.dev.configure(index, cfg.mode, cfg.pull); err != nil {
+			return err
+		}
+		if cfg.mode == enum.PinModeOutput {
+			if err := c.dev.write(index, c.outputs&(1<<index) != 0); err != nil {
+				return err
+			}
+		}
+	}
+	if c.interrupts {
+		if err := c.dev.setupInterrupts(); err != nil {
+			return err
+		}
+		for index := uint8(0); index < c.dev.getPins(); index++ {
+			if c.interruptMask&(1<<index) == 0 {
+				continue
+			}
+			if err := c.dev.enableInterrupt(index, true); err != nil {
+				return err
+			}
+		}
+	}
+	c.failures = 0
+	c.degraded = false
+	return nil
+}
+
+func (c *expanderChip) close() error {
+	close(c.closed)
+	c.watcherMux.Lock()
+	if c.done != nil {
+		c.stopIrq()
+		close(c.done)
+		c.done = nil
+	}
+	c.watchers = make(map[uint8]func(state bool))
+	c.watcherMux.Unlock()
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.dev.close()
+}
+
+type expanderPin struct {
+	pinIndex uint8
+	chip     *expanderChip
+}
+
+func (p expanderPin) ReadState() (bool, error) {
+	p.chip.mux.Lock()
+	defer p.chip.mux.Unlock()
+	if p.chip.degraded {
+		return false, ErrDegraded
+	}
+	levels, err := p.chip.dev.read()
+	if err = p.chip.result(err); err != nil {
+		return false, err
+	}
+	return levels&(1<<p.pinIndex) != 0, nil
+}
+
+func (p expanderPin) WriteState(state bool) error {
+	p.chip.mux.Lock()
+	defer p.chip.mux.Unlock()
+	// the level is kept even if the write fails, it is restored once the chip is reopened
+	if state {
+		p.chip.outputs |= 1 << p.pinIndex
+	} else {
+		p.chip.outputs &^= 1 << p.pinIndex
+	}
+	if p.chip.degraded {
+		return ErrDegraded
+	}
+	return p.chip.result(p.chip.dev.write(p.pinIndex, state))
+}
+
+func (p expanderPin) IsDegraded() bool {
+	p.chip.mux.Lock()
+	defer p.chip.mux.Unlock()
+	return p.chip.degraded
+}
+
+func (p expanderPin) Watch(handler func(state bool)) (func(), error) {
+	return p.chip.watch(p.pinIndex, handler)
+}
+
+func (p expanderPin) String() string {
+	return fmt.Sprintf("%v:%d", p.chip, p.pinIndex)
+}
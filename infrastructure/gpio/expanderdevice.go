@@ -0,0 +1,159 @@
+package gpio
+
+import (
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+// expanderDevice speaks the protocol of an expander type, the calls are serialized by the chip.
+type expanderDevice interface {
+	getPins() uint8
+	configure(index uint8, mode enum.PinMode, pull enum.Pull) error
+	// read returns the levels of all pins, bit i being pin i
+	read() (uint16, error)
+	write(index uint8, level bool) error
+	// setupInterrupts prepares the chip to assert a single INT line
+	setupInterrupts() error
+	enableInterrupt(index uint8, enable bool) error
+	close() error
+}
+
+// mcpRegisters are the addresses of the port A registers, port B follows each of them in the IOCON.BANK=0 layout.
+type mcpRegisters struct {
+	iodir, gpinten, intcon, iocon, gppu, gpio, olat byte
+}
+
+var (
+	mcp23017Registers = mcpRegisters{iodir: 0x00, gpinten: 0x04, intcon: 0x08, iocon: 0x0A, gppu: 0x0C, gpio: 0x12, olat: 0x14}
+	mcp23008Registers = mcpRegisters{iodir: 0x00, gpinten: 0x02, intcon: 0x04, iocon: 0x05, gppu: 0x06, gpio: 0x09, olat: 0x0A}
+)
+
+const mcpMirrorBit = 6
+
+// mcpDevice drives the MCP23017 and the MCP23008, which share the register set.
+type mcpDevice struct {
+	t    *i2cTarget
+	regs mcpRegisters
+	pins uint8
+}
+
+func (d *mcpDevice) getPins() uint8 {
+	return d.pins
+}
+
+func (d *mcpDevice) configure(index uint8, mode enum.PinMode, pull enum.Pull) error {
+	if err := d.t.updateBit(d.regs.iodir+index/8, index%8, mode == enum.PinModeInput); err != nil {
+		return err
+	}
+	if mode == enum.PinModeInput {
+		return d.t.updateBit(d.regs.gppu+index/8, index%8, pull == enum.PullUp)
+	}
+	return nil
+}
+
+func (d *mcpDevice) read() (uint16, error) {
+	buf := make([]byte, d.pins/8)
+	if err := d.t.readReg(d.regs.gpio, buf); err != nil {
+		return 0, err
+	}
+	levels := uint16(buf[0])
+	if len(buf) > 1 {
+		levels |= uint16(buf[1]) << 8
+	}
+	return levels, nil
+}
+
+func (d *mcpDevice) write(index uint8, level bool) error {
+	return d.t.updateBit(d.regs.olat+index/8, index%8, level)
+}
+
+func (d *mcpDevice) setupInterrupts() error {
+	if d.pins <= 8 {
+		return nil
+	}
+	// INTA and INTB are mirrored, so a single line has to be wired per chip
+	return d.t.updateBit(d.regs.iocon, mcpMirrorBit, true)
+}
+
+func (d *mcpDevice) enableInterrupt(index uint8, enable bool) error {
+	if enable {
+		// compare against the previous level, so both edges are reported
+		if err := d.t.updateBit(d.regs.intcon+index/8, index%8, false); err != nil {
+			return err
+		}
+	}
+	return d.t.updateBit(d.regs.gpinten+index/8, index%8, enable)
+}
+
+func (d *mcpDevice) close() error {
+	return d.t.close()
+}
+
+// pcfDevice drives the PCF8574 and the PCF8575. Their quasi-bidirectional pins have no registers,
+// a pin written high is an input with a weak pull-up and every change of an input asserts INT.
+type pcfDevice struct {
+	t     *i2cTarget
+	pins  uint8
+	latch uint16
+}
+
+func createPcfDevice(t *i2cTarget, pins uint8) *pcfDevice {
+	return &pcfDevice{
+		t:     t,
+		pins:  pins,
+		latch: 0xFFFF,
+	}
+}
+
+func (d *pcfDevice) getPins() uint8 {
+	return d.pins
+}
+
+// configure releases an input high, an output keeps the latch until the service writes its inactive level.
+func (d *pcfDevice) configure(index uint8, mode enum.PinMode, pull enum.Pull) error {
+	if mode == enum.PinModeInput {
+		d.latch |= 1 << index
+		return d.writeLatch()
+	}
+	return nil
+}
+
+func (d *pcfDevice) read() (uint16, error) {
+	buf := make([]byte, d.pins/8)
+	if err := d.t.read(buf); err != nil {
+		return 0, err
+	}
+	levels := uint16(buf[0])
+	if len(buf) > 1 {
+		levels |= uint16(buf[1]) << 8
+	}
+	return levels, nil
+}
+
+func (d *pcfDevice) write(index uint8, level bool) error {
+	if level {
+		d.latch |= 1 << index
+	} else {
+		d.latch &^= 1 << index
+	}
+	return d.writeLatch()
+}
+
+func (d *pcfDevice) writeLatch() error {
+	buf := []byte{byte(d.latch)}
+	if d.pins > 8 {
+		buf = append(buf, byte(d.latch>>8))
+	}
+	return d.t.write(buf)
+}
+
+func (d *pcfDevice) setupInterrupts() error {
+	return nil
+}
+
+func (d *pcfDevice) enableInterrupt(index uint8, enable bool) error {
+	return nil
+}
+
+func (d *pcfDevice) close() error {
+	return d.t.close()
+}
@@ -0,0 +1,165 @@
+package gpio
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+const (
+	longPressTime   = 800 * time.Millisecond
+	doublePressTime = 400 * time.Millisecond
+	// gestures waiting for the handler, a handler lagging behind that much loses the next ones
+	gestureQueueSize = 16
+)
+
+// CreatePairOptions maps the gestures of the input to the actions configured in the settings.
+func CreatePairOptions(pulseDuration time.Duration, settings domain.InputSettings, handler func(enum.Action)) PairOptions {
+	opts := PairOptions{
+		PulseDuration: pulseDuration,
+		Debounce:      settings.Debounce.Duration(),
+		InputPolarity: settings.InputPolarity,
+		InputPull:     settings.InputPull,
+	}
+	if !settings.HasActions() {
+		return opts
+	}
+	opts.DoublePress = settings.DoublePressAction != enum.ActionNone
+	opts.OnGesture = func(g enum.Gesture) {
+		if a := settings.GetAction(g); a != enum.ActionNone {
+			handler(a)
+		}
+	}
+	return opts
+}
+
+// gestureRecognizer turns the debounced levels of a push button into gestures.
+// The handler is called from a goroutine of its own, so the worker feeding the edges never waits for it.
+type gestureRecognizer struct {
+	handler     func(enum.Gesture)
+	doublePress bool
+	queue       chan enum.Gesture
+	done        chan struct{}
+
+	pressed  bool
+	long     bool
+	second   bool
+	awaiting bool
+	// edges counts the accepted edges, a timer fired for an older edge is ignored
+	edges   uint64
+	timer   *time.Timer
+	stopped bool
+	mux     sync.Mutex
+}
+
+func createGestureRecognizer(opts PairOptions) *gestureRecognizer {
+	if opts.OnGesture == nil {
+		return nil
+	}
+	g := &gestureRecognizer{
+		handler:     opts.OnGesture,
+		doublePress: opts.DoublePress,
+		queue:       make(chan enum.Gesture, gestureQueueSize),
+		done:        make(chan struct{}),
+	}
+	go g.dispatch()
+	return g
+}
+
+func (g *gestureRecognizer) dispatch() {
+	for {
+		select {
+		case <-g.done:
+			return
+		case gesture := <-g.queue:
+			g.handler(gesture)
+		}
+	}
+}
+
+func (g *gestureRecognizer) edge(pressed bool) {
+	var gestures []enum.Gesture
+	g.mux.Lock()
+	if g.stopped || pressed == g.pressed {
+		g.mux.Unlock()
+		return
+	}
+	g.pressed = pressed
+	g.edges++
+	edges := g.edges
+	g.stopTimer()
+	if pressed {
+		g.long = false
+		g.second = g.awaiting
+		g.awaiting = false
+		if g.second {
+			gestures = append(gestures, enum.GestureDoublePress)
+		}
+		g.timer = time.AfterFunc(longPressTime, func() { g.longPress(edges) })
+	} else {
+		gestures = append(gestures, enum.GestureRelease)
+		if !g.long && !g.second {
+			if g.doublePress {
+				g.awaiting = true
+				g.timer = time.AfterFunc(doublePressTime, func() { g.singlePress(edges) })
+			} else {
+				gestures = append(gestures, enum.GesturePress)
+			}
+		}
+	}
+	g.mux.Unlock()
+	g.emit(gestures...)
+}
+
+func (g *gestureRecognizer) longPress(edges uint64) {
+	g.mux.Lock()
+	if g.stopped || g.edges != edges || g.second {
+		g.mux.Unlock()
+		return
+	}
+	g.long = true
+	g.mux.Unlock()
+	g.emit(enum.GestureLongPress)
+}
+
+func (g *gestureRecognizer) singlePress(edges uint64) {
+	g.mux.Lock()
+	if g.stopped || g.edges != edges {
+		g.mux.Unlock()
+		return
+	}
+	g.awaiting = false
+	g.mux.Unlock()
+	g.emit(enum.GesturePress)
+}
+
+func (g *gestureRecognizer) emit(gestures ...enum.Gesture) {
+	for _, gesture := range gestures {
+		select {
+		case g.queue <- gesture:
+		default:
+			log.Println("Gesture handler is lagging behind, dropping", gesture)
+		}
+	}
+}
+
+func (g *gestureRecognizer) stopTimer() {
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+}
+
+func (g *gestureRecognizer) stop() {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	if g.stopped {
+		return
+	}
+	g.stopped = true
+	g.stopTimer()
+	close(g.done)
+}
@@ -0,0 +1,158 @@
+package gpio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+func createTestRecognizer(t *testing.T, doublePress bool) (*gestureRecognizer, chan enum.Gesture) {
+	t.Helper()
+	gestures := make(chan enum.Gesture, gestureQueueSize)
+	g := createGestureRecognizer(PairOptions{
+		DoublePress: doublePress,
+		OnGesture:   func(gesture enum.Gesture) { gestures <- gesture },
+	})
+	t.Cleanup(g.stop)
+	return g, gestures
+}
+
+func expectGestures(t *testing.T, gestures chan enum.Gesture, want ...enum.Gesture) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case got := <-gestures:
+			if got != w {
+				t.Fatalf("Expected %s, got %s", w, got)
+			}
+		case <-time.After(testTimeout):
+			t.Fatalf("Timed out waiting for %s", w)
+		}
+	}
+	expectNoGesture(t, gestures)
+}
+
+// expectNoGesture waits past the long press and double press windows, so a late gesture would have come.
+func expectNoGesture(t *testing.T, gestures chan enum.Gesture) {
+	t.Helper()
+	select {
+	case got := <-gestures:
+		t.Fatalf("Unexpected %s", got)
+	case <-time.After(longPressTime + doublePressTime):
+	}
+}
+
+func TestGesturePressAndRelease(t *testing.T) {
+	g, gestures := createTestRecognizer(t, false)
+
+	g.edge(true)
+	g.edge(false)
+	expectGestures(t, gestures, enum.GestureRelease, enum.GesturePress)
+}
+
+func TestGestureIgnoresRepeatedLevels(t *testing.T) {
+	g, gestures := createTestRecognizer(t, false)
+
+	g.edge(false)
+	expectNoGesture(t, gestures)
+	g.edge(true)
+	g.edge(true)
+	g.edge(false)
+	g.edge(false)
+	expectGestures(t, gestures, enum.GestureRelease, enum.GesturePress)
+}
+
+func TestGestureLongPress(t *testing.T) {
+	g, gestures := createTestRecognizer(t, false)
+
+	g.edge(true)
+	select {
+	case got := <-gestures:
+		if got != enum.GestureLongPress {
+			t.Fatalf("Expected %s, got %s", enum.GestureLongPress, got)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Timed out waiting for the long press")
+	}
+	// the release of a long press is not a press
+	g.edge(false)
+	expectGestures(t, gestures, enum.GestureRelease)
+}
+
+func TestGestureSinglePressWaitsForDoublePress(t *testing.T) {
+	g, gestures := createTestRecognizer(t, true)
+
+	start := time.Now()
+	g.edge(true)
+	g.edge(false)
+	expectGestures(t, gestures, enum.GestureRelease, enum.GesturePress)
+	if elapsed := time.Since(start); elapsed < doublePressTime {
+		t.Fatalf("Press was reported after %v, before the double press window closed", elapsed)
+	}
+}
+
+func TestGestureDoublePress(t *testing.T) {
+	g, gestures := createTestRecognizer(t, true)
+
+	g.edge(true)
+	g.edge(false)
+	g.edge(true)
+	g.edge(false)
+	// the second press is neither a press nor a long press
+	expectGestures(t, gestures, enum.GestureRelease, enum.GestureDoublePress, enum.GestureRelease)
+}
+
+func TestGestureDoublePressHeldIsNoLongPress(t *testing.T) {
+	g, gestures := createTestRecognizer(t, true)
+
+	g.edge(true)
+	g.edge(false)
+	g.edge(true)
+	expectGestures(t, gestures, enum.GestureRelease, enum.GestureDoublePress)
+	g.edge(false)
+	expectGestures(t, gestures, enum.GestureRelease)
+}
+
+func TestGestureStopDropsPending(t *testing.T) {
+	g, gestures := createTestRecognizer(t, true)
+
+	g.edge(true)
+	g.edge(false)
+	expectGestures(t, gestures, enum.GestureRelease, enum.GesturePress)
+	g.edge(true)
+	g.stop()
+	g.edge(false)
+	expectNoGesture(t, gestures)
+}
+
+func TestCreatePairOptionsMapsGestures(t *testing.T) {
+	if opts := CreatePairOptions(0, domain.InputSettings{}, func(enum.Action) {}); opts.OnGesture != nil || opts.DoublePress {
+		t.Fatal("Gestures are recognized without any action")
+	}
+
+	var actions []enum.Action
+	opts := CreatePairOptions(time.Second, domain.InputSettings{
+		Debounce:        domain.Milliseconds(30),
+		PressAction:     enum.ActionToggle,
+		LongPressAction: enum.ActionHouseOff,
+	}, func(a enum.Action) { actions = append(actions, a) })
+	if opts.PulseDuration != time.Second || opts.Debounce != 30*time.Millisecond {
+		t.Fatalf("Unexpected timings %v, %v", opts.PulseDuration, opts.Debounce)
+	}
+	if opts.DoublePress {
+		t.Fatal("Double press is awaited without a double press action")
+	}
+	for _, g := range []enum.Gesture{enum.GesturePress, enum.GestureDoublePress, enum.GestureLongPress, enum.GestureRelease} {
+		opts.OnGesture(g)
+	}
+	if len(actions) != 2 || actions[0] != enum.ActionToggle || actions[1] != enum.ActionHouseOff {
+		t.Fatalf("Unexpected actions %v", actions)
+	}
+
+	opts = CreatePairOptions(0, domain.InputSettings{DoublePressAction: enum.ActionOn}, func(enum.Action) {})
+	if !opts.DoublePress {
+		t.Fatal("Double press is not awaited with a double press action")
+	}
+}
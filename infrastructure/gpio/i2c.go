@@ -0,0 +1,151 @@
+package gpio
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/exp/io/i2c"
+)
+
+// i2cBus serializes the transfers on a bus and switches its multiplexers to the channel of the device.
+type i2cBus struct {
+	num      int
+	muxes    map[int]*i2c.Device
+	channels map[int]uint8
+	mux      sync.Mutex
+}
+
+func createI2cBus(num int) *i2cBus {
+	return &i2cBus{
+		num:      num,
+		muxes:    make(map[int]*i2c.Device),
+		channels: make(map[int]uint8),
+	}
+}
+
+func (b *i2cBus) open(address int) (*i2c.Device, error) {
+	return i2c.Open(&i2c.Devfs{Dev: fmt.Sprintf("/dev/i2c-%d", b.num)}, address)
+}
+
+// selectChannel has to be called under mux.
+func (b *i2cBus) selectChannel(muxAddress int, channel uint8) error {
+	dev, ok := b.muxes[muxAddress]
+	if !ok {
+		var err error
+		if dev, err = b.open(muxAddress); err != nil {
+			return err
+		}
+		b.muxes[muxAddress] = dev
+	} else if current, ok := b.channels[muxAddress]; ok && current == channel {
+		return nil
+	}
+	// every other mux on the bus is disconnected, so the addresses behind them do not collide
+	for address, other := range b.muxes {
+		if address == muxAddress {
+			continue
+		}
+		if current, ok := b.channels[address]; !ok || current != 0xFF {
+			if err := other.Write([]byte{0}); err != nil {
+				delete(b.channels, address)
+				return err
+			}
+			b.channels[address] = 0xFF
+		}
+	}
+	if err := dev.Write([]byte{1 << channel}); err != nil {
+		delete(b.channels, muxAddress)
+		return err
+	}
+	b.channels[muxAddress] = channel
+	return nil
+}
+
+func (b *i2cBus) close() error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	var ret error
+	for address, dev := range b.muxes {
+		if err := dev.Close(); err != nil && ret == nil {
+			ret = err
+		}
+		delete(b.muxes, address)
+	}
+	b.channels = make(map[int]uint8)
+	return ret
+}
+
+// i2cTarget is a device on a bus, possibly behind a multiplexer channel.
+type i2cTarget struct {
+	bus        *i2cBus
+	dev        *i2c.Device
+	muxAddress int
+	muxChannel uint8
+}
+
+func openI2cTarget(bus *i2cBus, address, muxAddress int, muxChannel uint8) (*i2cTarget, error) {
+	dev, err := bus.open(address)
+	if err != nil {
+		return nil, err
+	}
+	return &i2cTarget{
+		bus:        bus,
+		dev:        dev,
+		muxAddress: muxAddress,
+		muxChannel: muxChannel,
+	}, nil
+}
+
+func (t *i2cTarget) do(transfer func(dev *i2c.Device) error) error {
+	t.bus.mux.Lock()
+	defer t.bus.mux.Unlock()
+	if t.muxAddress != 0 {
+		if err := t.bus.selectChannel(t.muxAddress, t.muxChannel); err != nil {
+			return err
+		}
+	}
+	return transfer(t.dev)
+}
+
+func (t *i2cTarget) readReg(reg byte, buf []byte) error {
+	return t.do(func(dev *i2c.Device) error {
+		return dev.ReadReg(reg, buf)
+	})
+}
+
+func (t *i2cTarget) writeReg(reg byte, buf []byte) error {
+	return t.do(func(dev *i2c.Device) error {
+		return dev.WriteReg(reg, buf)
+	})
+}
+
+func (t *i2cTarget) read(buf []byte) error {
+	return t.do(func(dev *i2c.Device) error {
+		return dev.Read(buf)
+	})
+}
+
+func (t *i2cTarget) write(buf []byte) error {
+	return t.do(func(dev *i2c.Device) error {
+		return dev.Write(buf)
+	})
+}
+
+// updateBit sets or clears a single bit of the register.
+func (t *i2cTarget) updateBit(reg byte, bit uint8, set bool) error {
+	return t.do(func(dev *i2c.Device) error {
+		buf := make([]byte, 1)
+		if err := dev.ReadReg(reg, buf); err != nil {
+			return err
+		}
+		if set {
+			buf[0] |= 1 << bit
+		} else {
+			buf[0] &^= 1 << bit
+		}
+		return dev.WriteReg(reg, buf)
+	})
+}
+
+func (t *i2cTarget) close() error {
+	return t.dev.Close()
+}
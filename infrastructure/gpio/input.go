@@ -0,0 +1,148 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+)
+
+const (
+	InputModeInterrupt = "interrupt"
+	InputModePolling   = "polling"
+
+	inputBufferSize = 16
+)
+
+// input delivers the debounced level changes of an input pin to the pair worker.
+type input struct {
+	pin      domain.Pin
+	bus      *eventBus
+	events   chan bool
+	done     chan struct{}
+	debounce time.Duration
+	raw      bool
+	stable   bool
+	timer    *time.Timer
+	mux      sync.Mutex
+	stopFn   func()
+}
+
+func validateInputMode(mode string) error {
+	switch mode {
+	case "", InputModeInterrupt, InputModePolling:
+		return nil
+	default:
+		return fmt.Errorf("Unknown gpio input mode '%s'", mode)
+	}
+}
+
+// watchInput follows the pin through the driver edge detection, or by reading it every checkInterval
+// when the service runs in polling mode or the pin is unable to report edges.
+func (s *Service) watchInput(p domain.Pin, pin DriverPin, debounce time.Duration) *input {
+	in := &input{
+		pin:      p,
+		bus:      s.events,
+		events:   make(chan bool, inputBufferSize),
+		done:     make(chan struct{}),
+		debounce: debounce,
+		raw:      defaultPinState,
+		stable:   defaultPinState,
+	}
+	if ep, ok := pin.(EdgePin); ok && s.inputMode != InputModePolling {
+		v, err := pin.ReadState()
+		if err != nil {
+			log.Println("Pin", pin, "read error:", err)
+			in.bus.error(in.pin, err)
+		} else {
+			in.publish(v)
+		}
+		stop, err := ep.Watch(in.publish)
+		if err == nil {
+			in.stopFn = stop
+			return in
+		}
+		log.Println("Pin", pin, "edge detection unavailable, polling:", err)
+	}
+	go in.poll(pin)
+	return in
+}
+
+func (in *input) poll(pin DriverPin) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	failing := false
+	for {
+		select {
+		case <-in.done:
+			return
+		case <-ticker.C:
+		}
+		v, err := pin.ReadState()
+		if err != nil {
+			// a failure is reported once until the pin reads again, a degraded expander is logged by the driver
+			if !failing {
+				if !errors.Is(err, ErrDegraded) {
+					log.Println("Pin", pin, "read error:", err)
+				}
+				in.bus.error(in.pin, err)
+				failing = true
+			}
+			continue
+		}
+		failing = false
+		in.publish(v)
+	}
+}
+
+// publish accepts a raw level, which is passed on once it has been stable for the debounce window.
+func (in *input) publish(state bool) {
+	in.mux.Lock()
+	if in.raw == state {
+		in.mux.Unlock()
+		return
+	}
+	in.raw = state
+	if in.debounce > 0 {
+		if in.timer == nil {
+			in.timer = time.AfterFunc(in.debounce, in.settle)
+		} else {
+			in.timer.Reset(in.debounce)
+		}
+		in.mux.Unlock()
+		return
+	}
+	in.mux.Unlock()
+	in.settle()
+}
+
+func (in *input) settle() {
+	in.mux.Lock()
+	if in.raw == in.stable {
+		in.mux.Unlock()
+		return
+	}
+	state := in.raw
+	in.stable = state
+	in.mux.Unlock()
+	in.bus.input(in.pin, state)
+	select {
+	case in.events <- state:
+	case <-in.done:
+	}
+}
+
+func (in *input) stop() {
+	if in.stopFn != nil {
+		in.stopFn()
+	}
+	in.mux.Lock()
+	if in.timer != nil {
+		in.timer.Stop()
+	}
+	in.mux.Unlock()
+	close(in.done)
+}
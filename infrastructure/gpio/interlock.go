@@ -0,0 +1,109 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+)
+
+// interlock is a mutual-exclusion group of outputs, like the two directions of a motor.
+// Only the pair holding it may activate its output, another pair asks the holder to release it first
+// and takes it over once the dead-time after the release has passed.
+type interlock struct {
+	deadTime time.Duration
+
+	mux        sync.Mutex
+	owner      *pair
+	preempted  bool
+	lastOwner  *pair
+	releasedAt time.Time
+}
+
+// RegisterInterlock groups the outputs so that at most one of them is active at a time, with the dead-time
+// between the release of one and the activation of another. It has to be registered before the pairs of the outputs,
+// which have to be timed pairs.
+func (s *Service) RegisterInterlock(deadTime time.Duration, outputPins ...domain.Pin) error {
+	if !s.isActive() {
+		return inactiveErr
+	}
+	if deadTime < 0 {
+		return errors.New("Dead time must not be negative")
+	}
+	if err := s.IsPinRegistered(outputPins...); err != nil {
+		return err
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	for _, pin := range outputPins {
+		if _, ok := s.interlocks[pin]; ok {
+			return fmt.Errorf("Pin %v is already interlocked", pin)
+		}
+	}
+	l := &interlock{deadTime: deadTime}
+	for _, pin := range outputPins {
+		s.interlocks[pin] = l
+	}
+	return nil
+}
+
+// UnregisterInterlock removes the group of the outputs, once their pairs are unregistered.
+func (s *Service) UnregisterInterlock(outputPins ...domain.Pin) error {
+	if !s.isActive() {
+		return inactiveErr
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	for _, p := range s.pinPairs {
+		for _, pin := range outputPins {
+			if p.outputPin == pin {
+				return fmt.Errorf("Pin %v is still in use", pin)
+			}
+		}
+	}
+	for _, pin := range outputPins {
+		delete(s.interlocks, pin)
+	}
+	return nil
+}
+
+// acquire tells whether the pair may activate its output. The dead-time is only kept when the holder changes,
+// so a direction can be resumed right away.
+func (l *interlock) acquire(p *pair) bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.owner == p {
+		return true
+	}
+	if l.owner != nil {
+		l.preempted = true
+		return false
+	}
+	if l.lastOwner != p && time.Since(l.releasedAt) < l.deadTime {
+		return false
+	}
+	l.owner = p
+	return true
+}
+
+// isPreempted tells the holding pair to switch its output off and release the interlock.
+func (l *interlock) isPreempted(p *pair) bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.owner == p && l.preempted
+}
+
+// release is called once the output of the pair is off.
+func (l *interlock) release(p *pair) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.owner != p {
+		return
+	}
+	l.owner = nil
+	l.preempted = false
+	l.lastOwner = p
+	l.releasedAt = time.Now()
+}
@@ -2,27 +2,181 @@ package gpio
 
 import (
 	"strconv"
+	"sync"
+	"time"
 
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
 	"github.com/stianeikeland/go-rpio"
 )
 
-type rpioPin uint8
+const (
+	// the event detect status register latches the edges, so short pulses are not lost between checks
+	edgeCheckInterval = 5 * time.Millisecond
+	// above the flicker the LED drivers could show, the period is divided into maxDuty steps
+	pwmFrequency = 1000
+)
+
+// the native pins routed to the two PWM channels
+var rpioPwmPins = map[uint8]bool{12: true, 13: true, 18: true, 19: true}
+
+type rpioDriver struct {
+	gpioOpened bool
+
+	watchers   map[rpio.Pin]func(state bool)
+	watcherMux sync.Mutex
+	watchDone  chan struct{}
+}
+
+func createRpioDriver() *rpioDriver {
+	return &rpioDriver{
+		gpioOpened: false,
+		watchers:   make(map[rpio.Pin]func(state bool)),
+	}
+}
+
+func (d *rpioDriver) OpenPin(pin domain.Pin, mode enum.PinMode, pull enum.Pull) (DriverPin, error) {
+	if mode == enum.PinModePwm {
+		return d.openPwm(pin.Index)
+	}
+	return d.openIndex(pin.Index, mode, pull)
+}
+
+func (d *rpioDriver) openPwm(index uint8) (DriverPin, error) {
+	if !rpioPwmPins[index] {
+		return nil, ErrNoPwm
+	}
+	p, err := d.openIndex(index, enum.PinModePwm, enum.PullNone)
+	if err != nil {
+		return nil, err
+	}
+	rpio.SetFreq(p.pin, pwmFrequency*maxDuty)
+	return rpioPwmPin{p}, nil
+}
+
+func (d *rpioDriver) openIndex(index uint8, mode enum.PinMode, pull enum.Pull) (rpioPin, error) {
+	if !d.gpioOpened {
+		if err := rpio.Open(); err != nil {
+			return rpioPin{}, err
+		}
+		d.gpioOpened = true
+	}
+	p := rpioPin{
+		pin:    rpio.Pin(index),
+		driver: d,
+	}
+	rpio.PinMode(p.pin, mode.GetRpioMode())
+	if mode == enum.PinModeInput {
+		rpio.PullMode(p.pin, pull.GetRpioPull())
+	}
+	return p, nil
+}
+
+func (d *rpioDriver) Close() error {
+	d.watcherMux.Lock()
+	if d.watchDone != nil {
+		close(d.watchDone)
+		d.watchDone = nil
+	}
+	for pin := range d.watchers {
+		pin.Detect(rpio.NoEdge)
+	}
+	d.watchers = make(map[rpio.Pin]func(state bool))
+	d.watcherMux.Unlock()
+
+	if !d.gpioOpened {
+		return nil
+	}
+	d.gpioOpened = false
+	return rpio.Close()
+}
+
+func (d *rpioDriver) watch(pin rpio.Pin, edge rpio.Edge, handler func(state bool)) func() {
+	d.watcherMux.Lock()
+	defer d.watcherMux.Unlock()
+	pin.Detect(edge)
+	d.watchers[pin] = handler
+	if d.watchDone == nil {
+		d.watchDone = make(chan struct{})
+		go d.watchWorker(d.watchDone)
+	}
+	return func() {
+		d.watcherMux.Lock()
+		defer d.watcherMux.Unlock()
+		if _, ok := d.watchers[pin]; ok {
+			pin.Detect(rpio.NoEdge)
+			delete(d.watchers, pin)
+		}
+	}
+}
+
+func (d *rpioDriver) watchWorker(done chan struct{}) {
+	ticker := time.NewTicker(edgeCheckInterval)
+	defer ticker.Stop()
+	type event struct {
+		handler func(state bool)
+		state   bool
+	}
+	var events []event
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		events = events[:0]
+		d.watcherMux.Lock()
+		for pin, handler := range d.watchers {
+			if pin.EdgeDetected() {
+				events = append(events, event{handler, pin.Read() == rpio.High})
+			}
+		}
+		d.watcherMux.Unlock()
+		// handlers are called without the lock, so they can stop their own watch
+		for _, e := range events {
+			e.handler(e.state)
+		}
+	}
+}
+
+type rpioPin struct {
+	pin    rpio.Pin
+	driver *rpioDriver
+}
 
 func (p rpioPin) ReadState() (bool, error) {
-	pin := rpio.Pin(p)
-	return rpio.ReadPin(pin) == rpio.High, nil
+	return rpio.ReadPin(p.pin) == rpio.High, nil
 }
 
 func (p rpioPin) WriteState(state bool) error {
-	pin := rpio.Pin(p)
 	output := rpio.Low
 	if state {
 		output = rpio.High
 	}
-	rpio.WritePin(pin, output)
+	rpio.WritePin(p.pin, output)
 	return nil
 }
 
+func (p rpioPin) Watch(handler func(state bool)) (func(), error) {
+	return p.driver.watch(p.pin, rpio.AnyEdge, handler), nil
+}
+
 func (p rpioPin) String() string {
-	return strconv.Itoa(int(p))
+	return strconv.Itoa(int(p.pin))
+}
+
+type rpioPwmPin struct {
+	rpioPin
+}
+
+func (p rpioPwmPin) WriteState(state bool) error {
+	if state {
+		return p.WriteDuty(maxDuty)
+	}
+	return p.WriteDuty(0)
+}
+
+func (p rpioPwmPin) WriteDuty(duty uint8) error {
+	rpio.SetDutyCycle(p.pin, uint32(duty), maxDuty)
+	return nil
 }
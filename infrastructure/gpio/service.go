@@ -11,55 +11,171 @@ import (
 	"github.com/Erexo/Ventana/core/domain"
 	"github.com/Erexo/Ventana/core/enum"
 	"github.com/Erexo/Ventana/core/utils"
-	"github.com/racerxdl/go-mcp23017"
-	"github.com/stianeikeland/go-rpio"
+	"github.com/Erexo/Ventana/infrastructure/inventory"
 )
 
 const (
-	mcpBus          = 1
-	checkInterval   = 100 * time.Millisecond
-	timedPinTime    = 2 * time.Second // todo, move to entities
-	defaultPinState = true
+	checkInterval = 100 * time.Millisecond
+	// how long a latching relay may take to report the state it was pulsed to
+	feedbackTimeout = time.Second
+	// pin states are logical, logicalPin translates them to the levels of the wiring
+	defaultPinState = false
 )
 
 var (
 	inactiveErr = errors.New("Pin Manager is no longer active")
 )
 
-type workerPin interface {
-	ReadState() (bool, error)
-	WriteState(state bool) error
-	fmt.Stringer
+// PairOptions tune the behaviour of a registered pair.
+type PairOptions struct {
+	// PulseDuration is how long a timed pair keeps its output active, or how long an impulse pair pulses it.
+	PulseDuration time.Duration
+	// FeedbackPin reports the state of the relay driven by an impulse pair,
+	// it is wired like the input and shares its polarity, pull and debounce.
+	FeedbackPin domain.Pin
+	// Debounce is the time the input has to stay at a level before the change is accepted.
+	Debounce time.Duration
+	// OnGesture disconnects the input from the output, the recognized gestures are reported instead.
+	OnGesture func(enum.Gesture)
+	// DoublePress delays the press gesture until it is certain that no second press follows.
+	DoublePress bool
+	// InputPolarity and OutputPolarity tell which level of the pins means "on".
+	InputPolarity  enum.Polarity
+	OutputPolarity enum.Polarity
+	InputPull      enum.Pull
+	// Owner names the device using the pins in the diagnostics.
+	Owner string
+	// InitialState is the state a toggle, impulse or dimmable pair switches its output to once registered.
+	InitialState bool
+	// InitialLevel is the brightness of a dimmable pair in percent, full when not set.
+	InitialLevel uint8
+	// FadeDuration is how long a dimmable pair takes to fade through the whole range.
+	FadeDuration time.Duration
+	// HoldToDim lets a held input dim a dimmable pair, instead of reporting the long press.
+	HoldToDim bool
+	// OnOutput is called by the worker with every change of the output state and the time of it,
+	// unlike the events it is never dropped. It must not block the worker.
+	OnOutput func(state bool, at time.Time)
+	// OnLevel is called by the worker of a dimmable pair with every brightness set, it must not block either.
+	OnLevel func(level uint8)
 }
 
 type Service struct {
+	driver    Driver
+	inputMode string
+	// is keeps the commissioning off the pins claimed by the devices, including those failing to register
+	is         *inventory.Service
 	pinPairs   map[domain.Pin]*pair
-	openedMpcs map[uint8]*mcp23017.Device
+	inputPins  map[domain.Pin]*standaloneInput
+	outputPins map[domain.Pin]*standaloneOutput
+	bindings   map[domain.Pin]*binding
+	interlocks map[domain.Pin]*interlock
+	pulses     map[domain.Pin]*commissioningPulse
+	pins       map[domain.Pin]*pinInfo
 	pinMux     sync.Mutex
+	// pulseMux serialises the commissioning pulses, it is taken before pinMux
+	pulseMux sync.Mutex
 
-	outputGroup *sync.WaitGroup
-	isActive    bool
-	gpioOpened  bool
+	events  *eventBus
+	workers sync.WaitGroup
+	// ctx is the parent of the contexts of all workers, it is cancelled by Close
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func CreateService() *Service {
-	return &Service{
-		pinPairs:    make(map[domain.Pin]*pair),
-		openedMpcs:  make(map[uint8]*mcp23017.Device),
-		outputGroup: &sync.WaitGroup{},
-		isActive:    true,
-		gpioOpened:  false,
+func CreateService(driver Driver, inputMode string, is *inventory.Service) (*Service, error) {
+	if err := validateInputMode(inputMode); err != nil {
+		return nil, err
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Service{
+		driver:     driver,
+		inputMode:  inputMode,
+		is:         is,
+		pinPairs:   make(map[domain.Pin]*pair),
+		inputPins:  make(map[domain.Pin]*standaloneInput),
+		outputPins: make(map[domain.Pin]*standaloneOutput),
+		bindings:   make(map[domain.Pin]*binding),
+		interlocks: make(map[domain.Pin]*interlock),
+		pulses:     make(map[domain.Pin]*commissioningPulse),
+		pins:       make(map[domain.Pin]*pinInfo),
+		events:     createEventBus(),
+		ctx:        ctx,
+		cancel:     cancel,
+	}, nil
 }
 
+// pair is owned by its worker, the only goroutine touching the output and the state behind it.
+// The others send it commands and read the state it reports.
 type pair struct {
-	outputPin          domain.Pin
-	pairType           enum.PairType
-	timedCancel        context.CancelFunc
-	inputState         bool
-	outputState        bool
-	desiredOutputState bool
-	terminated         bool
+	outputPin     domain.Pin
+	feedbackPin   domain.Pin
+	pairType      enum.PairType
+	pulseDuration time.Duration
+	fadeDuration  time.Duration
+	holdToDim     bool
+	gestures      *gestureRecognizer
+	interlock     *interlock
+	onOutput      func(state bool, at time.Time)
+	onLevel       func(level uint8)
+
+	commands chan pairCommand
+	cancel   context.CancelFunc
+	// done is closed by the worker once the output is reset
+	done chan struct{}
+
+	// written by the worker under mux
+	mux         sync.Mutex
+	outputState bool
+	level       uint8
+}
+
+type pairCommandType uint8
+
+const (
+	// commandToggle flips the pair, a timed one is pulsed
+	commandToggle pairCommandType = iota
+	// commandSet switches the pair to the state, a timed one is pulsed or its pulse is cancelled
+	commandSet
+	// commandLevel sets the brightness of a dimmable pair, 0 switches it off
+	commandLevel
+	// commandBoundEdge is a change of a bound input driving the pair directly
+	commandBoundEdge
+)
+
+type pairCommand struct {
+	command pairCommandType
+	state   bool
+	level   uint8
+	// duration of the pulse of a timed pair in place of its own
+	duration time.Duration
+}
+
+// send hands the command over to the worker of the pair, it is dropped once the worker is done.
+// It is called without pinMux, the pair may be unregistered while the command waits for the worker.
+func (p *pair) send(c pairCommand) error {
+	select {
+	case p.commands <- c:
+		return nil
+	case <-p.done:
+		return fmt.Errorf("Pair of output pin %v is no longer registered", p.outputPin)
+	}
+}
+
+// switchState applies the command to the desired state of a toggle or impulse pair.
+func (c pairCommand) switchState(state bool) bool {
+	switch c.command {
+	case commandToggle, commandBoundEdge:
+		return !state
+	case commandSet:
+		return c.state
+	default:
+		return state
+	}
+}
+
+func (s *Service) isActive() bool {
+	return s.ctx.Err() == nil
 }
 
 func (s *Service) IsPinRegistered(pins ...domain.Pin) error {
@@ -67,68 +183,148 @@ func (s *Service) IsPinRegistered(pins ...domain.Pin) error {
 	defer s.pinMux.Unlock()
 	for k, v := range s.pinPairs {
 		for _, p := range pins {
-			if p == k || p == v.outputPin {
+			if p == k || p == v.outputPin || (v.pairType == enum.PairTypeImpulse && p == v.feedbackPin) {
 				return fmt.Errorf("Pin %v is already in use", p)
 			}
 		}
 	}
+	for _, p := range pins {
+		if _, ok := s.inputPins[p]; ok {
+			return fmt.Errorf("Pin %v is already in use", p)
+		}
+		if _, ok := s.outputPins[p]; ok {
+			return fmt.Errorf("Pin %v is already in use", p)
+		}
+		if _, ok := s.bindings[p]; ok {
+			return fmt.Errorf("Pin %v is already in use", p)
+		}
+	}
 	return nil
 }
 
 func (s *Service) GetPinState(inputPin domain.Pin) bool {
-	if !s.isActive {
+	if !s.isActive() {
 		return defaultPinState
 	}
 	s.pinMux.Lock()
 	defer s.pinMux.Unlock()
-	for k, v := range s.pinPairs {
-		if inputPin == k {
-			return v.outputState
-		}
+	p, ok := s.pinPairs[inputPin]
+	if !ok {
+		return defaultPinState
 	}
-	return defaultPinState
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.outputState
 }
 
-func (s *Service) RegisterPinPair(inputPin, outputPin domain.Pin, pairType enum.PairType) error {
-	if !s.isActive {
+// RegisterPinPair connects the input to the output. A pair without an input pin is driven by the commands only,
+// which address it by its output pin.
+func (s *Service) RegisterPinPair(inputPin, outputPin domain.Pin, pairType enum.PairType, opts PairOptions) error {
+	if !s.isActive() {
 		return inactiveErr
 	}
-	if err := s.IsPinRegistered(inputPin, outputPin); err != nil {
+	key := pairKey(inputPin, outputPin)
+	pins := []domain.Pin{key, outputPin}
+	switch pairType {
+	case enum.PairTypeToggle:
+	case enum.PairTypeDimmable:
+		// the software PWM would keep the I2C bus of an expander busy and flicker with its latency
+		if outputPin.IsExpanderPin() {
+			return fmt.Errorf("Pin %v is on an IO expander, a dimmable pair requires a native output pin", outputPin)
+		}
+	case enum.PairTypeTimed:
+		if opts.PulseDuration <= 0 {
+			return errors.New("Timed pair requires a positive pulse duration")
+		}
+	case enum.PairTypeImpulse:
+		if opts.PulseDuration <= 0 {
+			return errors.New("Impulse pair requires a positive pulse duration")
+		}
+		if opts.FeedbackPin == inputPin || opts.FeedbackPin == outputPin {
+			return fmt.Errorf("Pin %v can not be used as the feedback pin", opts.FeedbackPin)
+		}
+		pins = append(pins, opts.FeedbackPin)
+	default:
+		return fmt.Errorf("Unknown pair type '%d'", pairType)
+	}
+	if err := s.IsPinRegistered(pins...); err != nil {
 		return err
 	}
 
 	s.pinMux.Lock()
 	defer s.pinMux.Unlock()
-	err := s.registerPin(inputPin, enum.PinModeInput)
-	err = utils.ConcatErrors(err, s.registerPin(outputPin, enum.PinModeOutput))
-	if err != nil {
-		return err
+	interlock := s.interlocks[outputPin]
+	if interlock != nil && pairType != enum.PairTypeTimed {
+		return fmt.Errorf("Pin %v is interlocked, which is supported by timed pairs only", outputPin)
 	}
-
-	wi, err := s.createWorkerPin(inputPin)
-	if err != nil {
-		return err
+	var wi, wo, wf DriverPin
+	var err error
+	if inputPin.IsSet() {
+		if wi, err = s.openPin(inputPin, enum.PinModeInput, opts.InputPull, opts.InputPolarity, enum.PinRoleInput, opts.Owner); err != nil {
+			return err
+		}
+	}
+	var dimmer PwmPin
+	var closeDimmer func()
+	if pairType == enum.PairTypeDimmable {
+		dimmer, closeDimmer, err = s.openDimmer(outputPin, opts.OutputPolarity, opts.Owner)
+	} else {
+		wo, err = s.openPin(outputPin, enum.PinModeOutput, enum.PullNone, opts.OutputPolarity, enum.PinRoleOutput, opts.Owner)
 	}
-	wo, err := s.createWorkerPin(outputPin)
 	if err != nil {
+		s.releasePins(inputPin)
 		return err
 	}
+	if pairType == enum.PairTypeImpulse {
+		if wf, err = s.openPin(opts.FeedbackPin, enum.PinModeInput, opts.InputPull, opts.InputPolarity, enum.PinRoleFeedback, opts.Owner); err != nil {
+			s.releasePins(inputPin, outputPin)
+			return err
+		}
+	}
 
-	p := createPair(outputPin, pairType)
+	ctx, cancel := context.WithCancel(s.ctx)
+	p := createPair(outputPin, pairType, opts, cancel)
+	p.interlock = interlock
+	initialState := opts.InitialState && pairType != enum.PairTypeTimed
+	s.workers.Add(1)
 	switch pairType {
 	case enum.PairTypeToggle:
-		go s.togglePairWorker(wi, wo, p)
+		go s.togglePairWorker(ctx, s.watchPairInput(inputPin, wi, opts.Debounce), wo, p, initialState)
 	case enum.PairTypeTimed:
-		go s.timedPairWorker(wi, wo, p)
-	default:
-		log.Println("Unknown PairType:", pairType)
+		go s.timedPairWorker(ctx, s.watchPairInput(inputPin, wi, opts.Debounce), wo, p)
+	case enum.PairTypeImpulse:
+		// the relay kept its state, it is only pulsed if it differs from the initial one
+		if v, err := wf.ReadState(); err != nil {
+			log.Println("Pin", wf, "read error:", err)
+		} else {
+			p.outputState = v
+		}
+		go s.impulsePairWorker(ctx, s.watchPairInput(inputPin, wi, opts.Debounce), s.watchInput(opts.FeedbackPin, wf, opts.Debounce), wo, p, initialState)
+	case enum.PairTypeDimmable:
+		go s.dimmablePairWorker(ctx, s.watchPairInput(inputPin, wi, opts.Debounce), dimmer, closeDimmer, p, initialState)
 	}
-	s.pinPairs[inputPin] = p
+	s.pinPairs[key] = p
 	return nil
 }
 
+// pairKey is the pin a pair is addressed by, its input pin or the output pin of a pair without one.
+func pairKey(inputPin, outputPin domain.Pin) domain.Pin {
+	if inputPin.IsSet() {
+		return inputPin
+	}
+	return outputPin
+}
+
+// watchPairInput watches the input of the pair, a pair without one is given an input which never changes.
+func (s *Service) watchPairInput(inputPin domain.Pin, wi DriverPin, debounce time.Duration) *input {
+	if !inputPin.IsSet() {
+		return &input{done: make(chan struct{})}
+	}
+	return s.watchInput(inputPin, wi, debounce)
+}
+
 func (s *Service) UnregisterPinPair(inputPin, outputPin domain.Pin) error {
-	if !s.isActive {
+	if !s.isActive() {
 		return inactiveErr
 	}
 	s.pinMux.Lock()
@@ -137,215 +333,353 @@ func (s *Service) UnregisterPinPair(inputPin, outputPin domain.Pin) error {
 }
 
 func (s *Service) TogglePin(inputPin domain.Pin) error {
-	if !s.isActive {
+	return s.sendCommand(inputPin, pairCommand{command: commandToggle})
+}
+
+// SetPinActive switches the output of the pair on or off, a timed pair is pulsed or stopped even with its input held.
+func (s *Service) SetPinActive(inputPin domain.Pin, active bool) error {
+	return s.sendCommand(inputPin, pairCommand{command: commandSet, state: active})
+}
+
+// SetPinActiveFor activates the output of a timed pair for the duration, counted from the moment the output is on.
+func (s *Service) SetPinActiveFor(inputPin domain.Pin, duration time.Duration) error {
+	if duration <= 0 {
+		return errors.New("Pulse duration must be positive")
+	}
+	s.pinMux.Lock()
+	p, ok := s.pinPairs[inputPin]
+	s.pinMux.Unlock()
+	if ok && p.pairType != enum.PairTypeTimed {
+		return fmt.Errorf("Pin %v is not registered as a timed pair", inputPin)
+	}
+	return s.sendCommand(inputPin, pairCommand{command: commandSet, state: true, duration: duration})
+}
+
+// sendCommand passes the command to the worker of the pair. The pair is looked up under pinMux,
+// which is released before waiting for the worker, so a busy worker never holds up the other pins.
+func (s *Service) sendCommand(inputPin domain.Pin, c pairCommand) error {
+	if !s.isActive() {
 		return inactiveErr
 	}
 	s.pinMux.Lock()
-	defer s.pinMux.Unlock()
 	p, ok := s.pinPairs[inputPin]
+	s.pinMux.Unlock()
 	if !ok {
 		return errors.New(fmt.Sprintf("Pin %v is not registered as input pin", inputPin))
 	}
-	switch p.pairType {
-	case enum.PairTypeToggle:
-		p.desiredOutputState = !p.desiredOutputState
-	case enum.PairTypeTimed:
-		p.desiredOutputState = !defaultPinState
-		go func() {
-			if p.timedCancel != nil {
-				p.timedCancel()
-			}
-			ctx, f := context.WithCancel(context.Background())
-			p.timedCancel = f
-			time.Sleep(timedPinTime)
-			select {
-			case <-ctx.Done():
-			default:
-				p.desiredOutputState = defaultPinState
-			}
-		}()
-	default:
-		log.Println("Unknown PairType:", p.pairType)
-	}
-	return nil
+	return p.send(c)
 }
 
 func (s *Service) Close() error {
-	if !s.isActive {
+	s.pinMux.Lock()
+	if !s.isActive() {
+		s.pinMux.Unlock()
 		return inactiveErr
 	}
-	s.pinMux.Lock()
-	defer s.pinMux.Unlock()
 
 	var ret error
+	for pin, p := range s.pulses {
+		p.timer.Stop()
+		delete(s.pulses, pin)
+	}
 	for in, out := range s.pinPairs {
 		ret = utils.ConcatErrors(ret, s.internalUnregisterPinPair(in, out.outputPin))
 	}
-	s.isActive = false
-
-	s.outputGroup.Wait()
-	if s.gpioOpened {
-		ret = utils.ConcatErrors(ret, rpio.Close())
-		s.gpioOpened = false
+	for pin := range s.inputPins {
+		ret = utils.ConcatErrors(ret, s.internalUnregisterInputPin(pin))
 	}
-
-	for _, mpc := range s.openedMpcs {
-		if err := mpc.Close(); err != nil {
-			utils.ConcatErrors(ret, err)
-		}
+	for pin := range s.outputPins {
+		ret = utils.ConcatErrors(ret, s.internalUnregisterOutputPin(pin))
+	}
+	for pin := range s.bindings {
+		ret = utils.ConcatErrors(ret, s.internalUnregisterBinding(pin))
 	}
-	s.openedMpcs = make(map[uint8]*mcp23017.Device)
-	return ret
+	s.cancel()
+	// the input workers may still be waiting for the lock
+	s.pinMux.Unlock()
+
+	s.workers.Wait()
+	s.events.close()
+	return utils.ConcatErrors(ret, s.driver.Close())
 }
 
-func (s *Service) registerPin(pin domain.Pin, mode enum.PinMode) error {
-	if pin.IsMcpPin() {
-		mcpNum, err := pin.GetMcpNum()
-		if err != nil {
-			return err
-		}
-		mcp, ok := s.openedMpcs[mcpNum]
-		if !ok {
-			mcp, err = mcp23017.Open(mcpBus, mcpNum)
-			if err != nil {
-				return err
-			}
-			s.openedMpcs[mcpNum] = mcp
-		}
-		index := pin.GetPinIndex()
-		err = mcp.PinMode(index, mode.GetMcpMode())
-		if err != nil {
-			return err
-		}
-		if mode == enum.PinModeInput {
-			err = mcp.SetPullUp(index, true)
-			if err != nil {
-				return err
-			}
-		}
-	} else {
-		if !s.gpioOpened {
-			if err := rpio.Open(); err != nil {
-				return err
-			}
-			s.gpioOpened = true
-		}
-		rp := rpio.Pin(pin.GetPinIndex())
-		rpio.PinMode(rp, mode.GetRpioMode())
+// Start is called once the service is created, which is ready right away.
+func (s *Service) Start(ctx context.Context) error {
+	if !s.isActive() {
+		return inactiveErr
 	}
 	return nil
 }
 
+// Stop closes the service after the services using it, resetting the outputs left.
+// It gives up waiting for the workers once the context is done.
+func (s *Service) Stop(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Close()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("Gpio service did not stop in time: %w", ctx.Err())
+	}
+}
+
+// internalUnregisterPinPair waits for the worker to reset the output, so the pins can be reused right away.
 func (s *Service) internalUnregisterPinPair(inputPin, outputPin domain.Pin) error {
-	v, ok := s.pinPairs[inputPin]
+	key := pairKey(inputPin, outputPin)
+	v, ok := s.pinPairs[key]
 	if !ok {
-		return errors.New(fmt.Sprintf("Pin %v is not registered as input pin", inputPin))
+		return errors.New(fmt.Sprintf("Pin %v is not registered as input pin", key))
 	}
 	if v.outputPin != outputPin {
-		return errors.New(fmt.Sprintf("Pin %v is not an output pin assigned to input pin %v", outputPin, inputPin))
+		return errors.New(fmt.Sprintf("Pin %v is not an output pin assigned to input pin %v", outputPin, key))
 	}
 
-	v.terminated = true
-	delete(s.pinPairs, inputPin)
+	if v.gestures != nil {
+		v.gestures.stop()
+	}
+	v.cancel()
+	<-v.done
+	delete(s.pinPairs, key)
+	s.releasePins(key, outputPin)
+	if v.pairType == enum.PairTypeImpulse {
+		s.releasePins(v.feedbackPin)
+	}
 	return nil
 }
 
-func (s *Service) createWorkerPin(pin domain.Pin) (workerPin, error) {
-	if pin.IsMcpPin() {
-		mcpNum, err := pin.GetMcpNum()
-		if err != nil {
-			return nil, err
+func (s *Service) togglePairWorker(ctx context.Context, in *input, wo DriverPin, p *pair, desired bool) {
+	defer s.workers.Done()
+	defer close(p.done)
+	defer in.stop()
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	inputState := defaultPinState
+	for {
+		select {
+		case <-ctx.Done():
+			s.resetOutput(p, wo)
+			return
+		case c := <-p.commands:
+			desired = c.switchState(desired)
+		case v := <-in.events:
+			if p.gestures != nil {
+				p.gestures.edge(v)
+			} else if v != inputState {
+				inputState = v
+				desired = !desired
+			}
+		case <-ticker.C:
+			// retries a failed write
 		}
-		mcp, ok := s.openedMpcs[mcpNum]
-		if !ok {
-			return nil, errors.New(fmt.Sprintf("Mcp %v is not opened", mcpNum))
+		if desired == p.outputState {
+			continue
 		}
-
-		return mcpPin{
-			pinIndex: pin.GetPinIndex(),
-			mcpIndex: mcpNum,
-			mcp:      mcp,
-		}, nil
+		if err := wo.WriteState(desired); err != nil {
+			log.Println("Pin", wo, "write error:", err)
+			s.events.error(p.outputPin, err)
+			continue
+		}
+		s.setOutputState(p, desired)
 	}
-	return rpioPin(pin.GetPinIndex()), nil
 }
 
-func (s *Service) togglePairWorker(wi, wo workerPin, p *pair) {
-	s.outputGroup.Add(1)
-	defer s.outputGroup.Done()
-	var err error
-	for true {
-		time.Sleep(checkInterval)
-		if !s.isActive || p.terminated {
-			if err = wo.WriteState(defaultPinState); err != nil {
-				log.Println("Pin", wo, "inactive write error:", err)
+// timedPairWorker keeps the output active during a pulse or while the input is held.
+// An interlocked pair only activates its output once it holds the interlock.
+func (s *Service) timedPairWorker(ctx context.Context, in *input, wo DriverPin, p *pair) {
+	defer s.workers.Done()
+	defer close(p.done)
+	defer in.stop()
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	var pulse *time.Timer
+	var pulseEnd <-chan time.Time
+	// a pulse is timed from the moment the output is on, which an interlock may delay
+	var pending time.Duration
+	stopPulse := func() {
+		if pulse != nil {
+			pulse.Stop()
+		}
+		pulse, pulseEnd, pending = nil, nil, 0
+	}
+	startPulse := func(duration time.Duration) {
+		stopPulse()
+		pending = duration
+		if pending <= 0 {
+			pending = p.pulseDuration
+		}
+	}
+	defer stopPulse()
+	inputState := defaultPinState
+	// a pair released for the other output, or stopped, ignores its held input until the next edge
+	released := false
+	for {
+		select {
+		case <-ctx.Done():
+			s.resetOutput(p, wo)
+			if p.interlock != nil {
+				p.interlock.release(p)
+			}
+			return
+		case c := <-p.commands:
+			switch {
+			case c.command == commandToggle, c.command == commandSet && c.state, c.command == commandBoundEdge && c.state:
+				startPulse(c.duration)
+			case c.command == commandSet:
+				stopPulse()
+				released = true
 			}
-			break
+		case v := <-in.events:
+			if p.gestures != nil {
+				p.gestures.edge(v)
+			} else {
+				inputState = v
+				released = false
+			}
+		case <-pulseEnd:
+			pulse, pulseEnd = nil, nil
+		case <-ticker.C:
+			// polls the interlock and retries a failed write
+		}
+		if p.interlock != nil && p.interlock.isPreempted(p) {
+			stopPulse()
+			released = true
 		}
-		if p.desiredOutputState == p.outputState {
-			v, err := wi.ReadState()
-			if err != nil {
-				log.Println("Pin", wi, "read error:", err)
+		active := pending > 0 || pulseEnd != nil || (inputState != defaultPinState && !released)
+		if active != p.outputState {
+			if active && p.interlock != nil && !p.interlock.acquire(p) {
 				continue
-			} else if v == p.inputState {
+			}
+			if err := wo.WriteState(active); err != nil {
+				log.Println("Pin", wo, "write error:", err)
+				s.events.error(p.outputPin, err)
 				continue
 			}
-			p.inputState = v
-			p.desiredOutputState = !p.outputState
+			s.setOutputState(p, active)
+			// the other output may only follow once this one is certainly off
+			if !active && p.interlock != nil {
+				p.interlock.release(p)
+			}
 		}
-		if err = wo.WriteState(p.desiredOutputState); err != nil {
-			log.Println("Pin", wo, "write error:", err)
-		} else {
-			p.outputState = p.desiredOutputState
+		if pending > 0 && p.outputState {
+			pulse = time.NewTimer(pending)
+			pulseEnd, pending = pulse.C, 0
 		}
 	}
 }
 
-func (s *Service) timedPairWorker(wi, wo workerPin, p *pair) {
-	s.outputGroup.Add(1)
-	defer s.outputGroup.Done()
-	var err error
-	for true {
-		time.Sleep(checkInterval)
-		if !s.isActive || p.terminated {
-			if err = wo.WriteState(defaultPinState); err != nil {
-				log.Println("Pin", wo, "inactive write error:", err)
+// impulsePairWorker pulses the relay until the feedback reports the desired state,
+// a feedback change that was not requested comes from a manual operation and is taken over.
+func (s *Service) impulsePairWorker(ctx context.Context, in, feedback *input, wo DriverPin, p *pair, desired bool) {
+	defer s.workers.Done()
+	defer close(p.done)
+	defer in.stop()
+	defer feedback.stop()
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	inputState := defaultPinState
+	var pulseEnd <-chan time.Time
+	var feedbackDeadline time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			s.resetOutput(p, wo)
+			return
+		case c := <-p.commands:
+			desired = c.switchState(desired)
+		case v := <-in.events:
+			if p.gestures != nil {
+				p.gestures.edge(v)
+			} else if v != inputState {
+				inputState = v
+				desired = !desired
 			}
-			break
-		}
-		if p.desiredOutputState != defaultPinState {
-			if p.outputState == defaultPinState {
-				if err = wo.WriteState(!p.outputState); err != nil {
-					log.Println("Pin", wo, "write error:", err)
-				} else {
-					p.outputState = !p.outputState
-				}
+		case v := <-feedback.events:
+			if feedbackDeadline.IsZero() && v != p.outputState {
+				desired = v
 			}
-		} else {
-			v, err := wi.ReadState()
-			if err != nil {
-				log.Println("Pin", wi, "read error:", err)
+			s.setOutputState(p, v)
+			feedbackDeadline = time.Time{}
+		case <-pulseEnd:
+			if err := wo.WriteState(defaultPinState); err != nil {
+				log.Println("Pin", wo, "write error:", err)
+				s.events.error(p.outputPin, err)
+				pulseEnd = time.After(checkInterval)
 				continue
 			}
-			p.inputState = v
-			if p.inputState != p.outputState {
-				if err = wo.WriteState(p.inputState); err != nil {
-					log.Println("Pin", wo, "write error:", err)
-				} else {
-					p.outputState = p.inputState
-				}
+			pulseEnd = nil
+		case <-ticker.C:
+			// checks the feedback deadline
+		}
+		if pulseEnd != nil {
+			continue
+		}
+		if !feedbackDeadline.IsZero() {
+			if time.Now().Before(feedbackDeadline) {
+				continue
 			}
+			log.Println("Pin", wo, "relay did not report the requested state")
+			s.events.error(p.outputPin, errors.New("Relay did not report the requested state"))
+			feedbackDeadline = time.Time{}
+			desired = p.outputState
+		}
+		if desired == p.outputState {
+			continue
+		}
+		if err := wo.WriteState(!defaultPinState); err != nil {
+			log.Println("Pin", wo, "write error:", err)
+			s.events.error(p.outputPin, err)
+			continue
 		}
+		pulseEnd = time.After(p.pulseDuration)
+		feedbackDeadline = time.Now().Add(p.pulseDuration + feedbackTimeout)
 	}
 }
 
-func createPair(outputPin domain.Pin, pairType enum.PairType) *pair {
+// resetOutput switches the output off once the worker is cancelled, the state kept for the next start is not touched.
+func (s *Service) resetOutput(p *pair, wo DriverPin) {
+	if err := wo.WriteState(defaultPinState); err != nil {
+		log.Println("Pin", wo, "inactive write error:", err)
+		s.events.error(p.outputPin, err)
+	}
+}
+
+// setOutputState records the logical state of the pair output and announces its change,
+// it is called by the worker of the pair only.
+func (s *Service) setOutputState(p *pair, state bool) {
+	p.mux.Lock()
+	changed := p.outputState != state
+	p.outputState = state
+	p.mux.Unlock()
+	if changed {
+		s.events.output(p.outputPin, state)
+		if p.onOutput != nil {
+			p.onOutput(state, time.Now())
+		}
+	}
+}
+
+func createPair(outputPin domain.Pin, pairType enum.PairType, opts PairOptions, cancel context.CancelFunc) *pair {
+	level := opts.InitialLevel
+	if level == 0 || level > maxDuty {
+		level = maxDuty
+	}
 	return &pair{
-		outputPin:          outputPin,
-		pairType:           pairType,
-		inputState:         defaultPinState,
-		outputState:        defaultPinState,
-		desiredOutputState: defaultPinState,
-		terminated:         false,
+		outputPin:     outputPin,
+		feedbackPin:   opts.FeedbackPin,
+		pairType:      pairType,
+		pulseDuration: opts.PulseDuration,
+		fadeDuration:  opts.FadeDuration,
+		holdToDim:     opts.HoldToDim,
+		gestures:      createGestureRecognizer(opts),
+		onOutput:      opts.OnOutput,
+		onLevel:       opts.OnLevel,
+		commands:      make(chan pairCommand),
+		cancel:        cancel,
+		done:          make(chan struct{}),
+		outputState:   defaultPinState,
+		level:         level,
 	}
 }
@@ -0,0 +1,527 @@
+package gpio
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+	"github.com/Erexo/Ventana/infrastructure/inventory"
+)
+
+// how long the workers are given to react to an edge or a command
+const testTimeout = 2 * time.Second
+
+var (
+	testInputPin    = domain.CreateRpioPin(2)
+	testOutputPin   = domain.CreateRpioPin(3)
+	testFeedbackPin = domain.CreateRpioPin(4)
+	testDownInput   = domain.CreateRpioPin(5)
+	testDownOutput  = domain.CreateRpioPin(6)
+	testUpInput     = domain.CreateRpioPin(7)
+	testUpOutput    = domain.CreateRpioPin(8)
+)
+
+// closingDriver keeps the levels the pins were left at when the service closed the driver.
+type closingDriver struct {
+	*SimulatedDriver
+	levels map[domain.Pin]bool
+}
+
+func (d *closingDriver) Close() error {
+	d.mux.Lock()
+	d.levels = make(map[domain.Pin]bool, len(d.SimulatedDriver.levels))
+	for pin, level := range d.SimulatedDriver.levels {
+		d.levels[pin] = level
+	}
+	d.mux.Unlock()
+	return d.SimulatedDriver.Close()
+}
+
+func createTestService(t *testing.T, driver Driver) *Service {
+	t.Helper()
+	s, err := CreateService(driver, InputModeInterrupt, inventory.CreateService())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return s
+}
+
+// testOptions wire the inputs and outputs active high, a released input is pulled down.
+func testOptions() PairOptions {
+	return PairOptions{
+		InputPolarity:  enum.PolarityActiveHigh,
+		OutputPolarity: enum.PolarityActiveHigh,
+		InputPull:      enum.PullDown,
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitForLevel(t *testing.T, d *SimulatedDriver, pin domain.Pin, level bool) {
+	t.Helper()
+	waitFor(t, "the level of pin "+pin.String(), func() bool {
+		v, err := d.GetState(pin)
+		return err == nil && v == level
+	})
+}
+
+func setInput(t *testing.T, d *SimulatedDriver, pin domain.Pin, state bool) {
+	t.Helper()
+	if err := d.SetInput(pin, state); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTogglePairFollowsInputEdges(t *testing.T) {
+	d := CreateSimulatedDriver()
+	s := createTestService(t, d)
+	if err := s.RegisterPinPair(testInputPin, testOutputPin, enum.PairTypeToggle, testOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	setInput(t, d, testInputPin, true)
+	waitForLevel(t, d, testOutputPin, true)
+	waitFor(t, "the pair state", func() bool { return s.GetPinState(testInputPin) })
+	// the wall switch is flipped back
+	setInput(t, d, testInputPin, false)
+	waitForLevel(t, d, testOutputPin, false)
+
+	if err := s.TogglePin(testInputPin); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, d, testOutputPin, true)
+	if err := s.SetPinActive(testInputPin, false); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, d, testOutputPin, false)
+}
+
+func TestOutputsStartInactive(t *testing.T) {
+	d := CreateSimulatedDriver()
+	s := createTestService(t, d)
+	opts := testOptions()
+	opts.OutputPolarity = enum.PolarityActiveLow
+	if err := s.RegisterPinPair(testInputPin, testOutputPin, enum.PairTypeToggle, opts); err != nil {
+		t.Fatal(err)
+	}
+	// the simulated outputs power on low, which is active here
+	if v, err := d.GetState(testOutputPin); err != nil || !v {
+		t.Fatalf("Active low output is at %v after the registration, %v", v, err)
+	}
+	if s.GetPinState(testInputPin) {
+		t.Fatal("Pair is on after the registration")
+	}
+}
+
+func TestTimedPairPulsesAndFollowsHeldInput(t *testing.T) {
+	d := CreateSimulatedDriver()
+	s := createTestService(t, d)
+	opts := testOptions()
+	opts.PulseDuration = 100 * time.Millisecond
+	if err := s.RegisterPinPair(testInputPin, testOutputPin, enum.PairTypeTimed, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// a held input keeps the output on past the pulse duration
+	setInput(t, d, testInputPin, true)
+	waitForLevel(t, d, testOutputPin, true)
+	time.Sleep(2 * opts.PulseDuration)
+	if v, _ := d.GetState(testOutputPin); !v {
+		t.Error("Output went off with the input held")
+	}
+	setInput(t, d, testInputPin, false)
+	waitForLevel(t, d, testOutputPin, false)
+
+	sent := time.Now()
+	if err := s.TogglePin(testInputPin); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, d, testOutputPin, true)
+	waitForLevel(t, d, testOutputPin, false)
+	if elapsed := time.Since(sent); elapsed < opts.PulseDuration {
+		t.Errorf("Pulse ended after %v, expected at least %v", elapsed, opts.PulseDuration)
+	}
+
+	// a pulse is cut short by switching the pair off
+	if err := s.SetPinActiveFor(testInputPin, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, d, testOutputPin, true)
+	if err := s.SetPinActive(testInputPin, false); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, d, testOutputPin, false)
+}
+
+func TestImpulsePairPulsesUntilFeedback(t *testing.T) {
+	d := CreateSimulatedDriver()
+	s := createTestService(t, d)
+	opts := testOptions()
+	opts.PulseDuration = 50 * time.Millisecond
+	opts.FeedbackPin = testFeedbackPin
+	if err := s.RegisterPinPair(testInputPin, testOutputPin, enum.PairTypeImpulse, opts); err != nil {
+		t.Fatal(err)
+	}
+	events, cancel := s.Subscribe(testOutputPin)
+	defer cancel()
+
+	// the latching relay flips on the pulse and reports it through the feedback
+	setInput(t, d, testInputPin, true)
+	waitForLevel(t, d, testOutputPin, true)
+	setInput(t, d, testFeedbackPin, true)
+	waitForLevel(t, d, testOutputPin, false)
+	waitFor(t, "the pair state", func() bool { return s.GetPinState(testInputPin) })
+
+	// a relay operated by hand is taken over without a pulse
+	setInput(t, d, testFeedbackPin, false)
+	waitFor(t, "the pair state", func() bool { return !s.GetPinState(testInputPin) })
+	if v, _ := d.GetState(testOutputPin); v {
+		t.Error("Relay operated by hand was pulsed")
+	}
+
+	// a relay not reporting the requested state is reported as an error
+	if err := s.TogglePin(testInputPin); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.After(opts.PulseDuration + feedbackTimeout + testTimeout)
+	for reported := false; !reported; {
+		select {
+		case e := <-events:
+			reported = e.Type == enum.PinEventError
+		case <-deadline:
+			t.Fatal("Missing feedback was not reported")
+		}
+	}
+	if s.GetPinState(testInputPin) {
+		t.Error("Pair state follows the request instead of the feedback")
+	}
+}
+
+func TestCommandsRaceUnregister(t *testing.T) {
+	d := CreateSimulatedDriver()
+	s := createTestService(t, d)
+	opts := testOptions()
+	opts.PulseDuration = 20 * time.Millisecond
+	for i := 0; i < 50; i++ {
+		pairType := enum.PairTypeToggle
+		if i%2 == 1 {
+			pairType = enum.PairTypeTimed
+		}
+		if err := s.RegisterPinPair(testInputPin, testOutputPin, pairType, opts); err != nil {
+			t.Fatal(err)
+		}
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for j := 0; j < 4; j++ {
+			wg.Add(1)
+			go func(j int) {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					if j%2 == 0 {
+						s.TogglePin(testInputPin)
+					} else {
+						s.SetPinActive(testInputPin, j%4 == 1)
+					}
+				}
+			}(j)
+		}
+		time.Sleep(time.Millisecond)
+		if err := s.UnregisterPinPair(testInputPin, testOutputPin); err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := d.GetState(testOutputPin); v {
+			t.Fatal("Output left on by an unregistered pair")
+		}
+		if err := s.TogglePin(testInputPin); err == nil {
+			t.Fatal("Command accepted by an unregistered pair")
+		}
+		close(stop)
+		wg.Wait()
+	}
+}
+
+func TestBusyWorkerDoesNotBlockOtherPins(t *testing.T) {
+	d := CreateSimulatedDriver()
+	s := createTestService(t, d)
+	busy, release := make(chan struct{}), make(chan struct{})
+	var once, released sync.Once
+	unblock := func() { released.Do(func() { close(release) }) }
+	// runs before the service is closed, should the test fail with the worker held up
+	t.Cleanup(unblock)
+	opts := testOptions()
+	// the worker of the first pair is held up reporting its output
+	opts.OnOutput = func(bool, time.Time) {
+		once.Do(func() {
+			close(busy)
+			<-release
+		})
+	}
+	if err := s.RegisterPinPair(testInputPin, testOutputPin, enum.PairTypeToggle, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterPinPair(testUpInput, testUpOutput, enum.PairTypeToggle, testOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.TogglePin(testInputPin); err != nil {
+		t.Fatal(err)
+	}
+	<-busy
+	waiting := make(chan error)
+	go func() {
+		waiting <- s.TogglePin(testInputPin)
+	}()
+	// gives the command the time to wait for the worker
+	time.Sleep(10 * time.Millisecond)
+
+	// the command waiting for the busy worker does not hold the other pins up
+	done := make(chan error)
+	go func() {
+		done <- s.TogglePin(testUpInput)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Command to an idle pair waited for a busy worker")
+	}
+	waitForLevel(t, d, testUpOutput, true)
+
+	unblock()
+	if err := <-waiting; err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, d, testOutputPin, false)
+}
+
+func TestCloseWithCommandsInFlight(t *testing.T) {
+	d := &closingDriver{SimulatedDriver: CreateSimulatedDriver()}
+	s := createTestService(t, d)
+	opts := testOptions()
+	opts.PulseDuration = time.Minute
+	if err := s.RegisterPinPair(testInputPin, testOutputPin, enum.PairTypeToggle, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterInterlock(10*time.Millisecond, testDownOutput, testUpOutput); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterPinPair(testDownInput, testDownOutput, enum.PairTypeTimed, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterPinPair(testUpInput, testUpOutput, enum.PairTypeTimed, opts); err != nil {
+		t.Fatal(err)
+	}
+	dimmer := domain.CreateRpioPin(9)
+	if err := s.RegisterPinPair(domain.CreateRpioPin(10), dimmer, enum.PairTypeDimmable, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetPinLevel(domain.CreateRpioPin(10), 50); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, pin := range []domain.Pin{testInputPin, testDownInput, testUpInput, domain.CreateRpioPin(10)} {
+		wg.Add(2)
+		go func(pin domain.Pin) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				s.TogglePin(pin)
+				s.SetPinActive(pin, true)
+			}
+		}(pin)
+		go func(pin domain.Pin) {
+			defer wg.Done()
+			state := false
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// the driver forgets the pins once closed
+				state = !state
+				d.SetInput(pin, state)
+			}
+		}(pin)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- s.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Close did not return with commands in flight")
+	}
+	close(stop)
+	wg.Wait()
+
+	for _, pin := range []domain.Pin{testOutputPin, testDownOutput, testUpOutput, dimmer} {
+		if d.levels[pin] {
+			t.Errorf("Output %v left on after Close", pin)
+		}
+	}
+	if err := s.TogglePin(testInputPin); !errors.Is(err, inactiveErr) {
+		t.Errorf("Command after Close returned %v", err)
+	}
+}
+
+func TestInterlockPreemptsWithDeadTime(t *testing.T) {
+	d := CreateSimulatedDriver()
+	s := createTestService(t, d)
+	const deadTime = 150 * time.Millisecond
+	opts := testOptions()
+	opts.PulseDuration = time.Minute
+	if err := s.RegisterInterlock(deadTime, testDownOutput, testUpOutput); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterPinPair(testDownInput, testDownOutput, enum.PairTypeTimed, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterPinPair(testUpInput, testUpOutput, enum.PairTypeTimed, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterPinPair(testInputPin, testDownOutput, enum.PairTypeToggle, opts); err == nil {
+		t.Error("Interlocked output accepted by a toggle pair")
+	}
+	events, cancel := s.Subscribe(testDownOutput, testUpOutput)
+	defer cancel()
+
+	if err := s.SetPinActive(testDownInput, true); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, d, testDownOutput, true)
+	if err := s.SetPinActive(testUpInput, true); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, d, testUpOutput, true)
+
+	states := map[domain.Pin]bool{testDownOutput: true}
+	var releasedAt time.Time
+	for e := range events {
+		if e.Type != enum.PinEventOutput {
+			continue
+		}
+		states[e.Pin] = e.State
+		if states[testDownOutput] && states[testUpOutput] {
+			t.Fatal("Both interlocked outputs were on")
+		}
+		if e.Pin == testDownOutput && !e.State {
+			releasedAt = e.Time
+		}
+		if e.Pin == testUpOutput && e.State {
+			if gap := e.Time.Sub(releasedAt); releasedAt.IsZero() || gap < deadTime {
+				t.Errorf("Preempting output came on %v after the release, expected at least %v", gap, deadTime)
+			}
+			break
+		}
+	}
+	// the preempted pulse is not resumed once the holder is done
+	if err := s.SetPinActive(testUpInput, false); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, d, testUpOutput, false)
+	time.Sleep(deadTime + 2*checkInterval)
+	if v, _ := d.GetState(testDownOutput); v {
+		t.Error("Preempted output came back on")
+	}
+
+	// the same direction is resumed without the dead-time
+	resumed := time.Now()
+	if err := s.SetPinActive(testUpInput, true); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(t, d, testUpOutput, true)
+	if elapsed := time.Since(resumed); elapsed >= deadTime {
+		t.Errorf("Resumed output waited %v for the dead-time", elapsed)
+	}
+}
+
+func TestDimmablePairReportsLogicalLevel(t *testing.T) {
+	d := CreateSimulatedDriver()
+	s := createTestService(t, d)
+	opts := testOptions()
+	opts.FadeDuration = 200 * time.Millisecond
+	var levels []uint8
+	var mux sync.Mutex
+	opts.OnLevel = func(level uint8) {
+		mux.Lock()
+		defer mux.Unlock()
+		levels = append(levels, level)
+	}
+	if err := s.RegisterPinPair(testInputPin, testOutputPin, enum.PairTypeDimmable, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// the simulated pins have no hardware PWM, so the output is driven by the software one
+	if err := s.SetPinLevel(testInputPin, 50); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the pair state", func() bool { return s.GetPinState(testInputPin) })
+	flips, last := 0, false
+	deadline := time.Now().Add(20 * softPwmPeriod)
+	for time.Now().Before(deadline) {
+		if v, _ := d.GetState(testOutputPin); v != last {
+			flips, last = flips+1, v
+		}
+		diag, err := s.GetDiagnostics()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range diag.Pins {
+			if p.Pin == testOutputPin && !p.State {
+				t.Fatal("Diagnostics show the dimmed output off")
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if flips < 2 {
+		t.Fatalf("Output flipped %d times, the software PWM is not running", flips)
+	}
+	mux.Lock()
+	if len(levels) != 1 || levels[0] != 50 {
+		t.Errorf("Reported levels %v, expected [50]", levels)
+	}
+	mux.Unlock()
+
+	if err := s.SetPinActive(testInputPin, false); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the pair to switch off", func() bool { return !s.GetPinState(testInputPin) })
+	waitForLevel(t, d, testOutputPin, false)
+	if level := s.GetPinLevel(testInputPin); level != 50 {
+		t.Errorf("Level %d kept while off, expected 50", level)
+	}
+}
@@ -0,0 +1,133 @@
+package gpio
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+// SimulatedDriver keeps pin levels in memory, so the server can run without a Raspberry Pi.
+// Tests drive the inputs with SetInput and observe the outputs with GetState.
+type SimulatedDriver struct {
+	levels   map[domain.Pin]bool
+	modes    map[domain.Pin]enum.PinMode
+	watchers map[domain.Pin]func(state bool)
+	mux      sync.Mutex
+}
+
+func CreateSimulatedDriver() *SimulatedDriver {
+	return &SimulatedDriver{
+		levels:   make(map[domain.Pin]bool),
+		modes:    make(map[domain.Pin]enum.PinMode),
+		watchers: make(map[domain.Pin]func(state bool)),
+	}
+}
+
+func (d *SimulatedDriver) OpenPin(pin domain.Pin, mode enum.PinMode, pull enum.Pull) (DriverPin, error) {
+	if !pin.IsSet() {
+		return nil, fmt.Errorf("Invalid pin '%v'", pin)
+	}
+	// the simulated board has no hardware PWM, the dimmable outputs use the software one
+	if mode == enum.PinModePwm {
+		return nil, ErrNoPwm
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if _, ok := d.levels[pin]; !ok {
+		// a floating input is read as high, as on the real hardware
+		d.levels[pin] = pull != enum.PullDown
+	}
+	d.modes[pin] = mode
+	return simulatedPin{
+		pin:    pin,
+		driver: d,
+	}, nil
+}
+
+func (d *SimulatedDriver) Close() error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.levels = make(map[domain.Pin]bool)
+	d.modes = make(map[domain.Pin]enum.PinMode)
+	d.watchers = make(map[domain.Pin]func(state bool))
+	return nil
+}
+
+// SetInput changes the level of an opened input pin, as if the wired switch had changed.
+// The edge is delivered synchronously to the watcher of the pin.
+func (d *SimulatedDriver) SetInput(pin domain.Pin, state bool) error {
+	d.mux.Lock()
+	mode, ok := d.modes[pin]
+	if !ok {
+		d.mux.Unlock()
+		return fmt.Errorf("Pin %v is not opened", pin)
+	}
+	if mode != enum.PinModeInput {
+		d.mux.Unlock()
+		return fmt.Errorf("Pin %v is not an input pin", pin)
+	}
+	changed := d.levels[pin] != state
+	d.levels[pin] = state
+	handler := d.watchers[pin]
+	d.mux.Unlock()
+
+	if changed && handler != nil {
+		handler(state)
+	}
+	return nil
+}
+
+// GetState returns the current level of an opened pin.
+func (d *SimulatedDriver) GetState(pin domain.Pin) (bool, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	state, ok := d.levels[pin]
+	if !ok {
+		return false, fmt.Errorf("Pin %v is not opened", pin)
+	}
+	return state, nil
+}
+
+func (d *SimulatedDriver) watch(pin domain.Pin, handler func(state bool)) func() {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.watchers[pin] = handler
+	return func() {
+		d.mux.Lock()
+		defer d.mux.Unlock()
+		delete(d.watchers, pin)
+	}
+}
+
+func (d *SimulatedDriver) write(pin domain.Pin, state bool) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if _, ok := d.levels[pin]; !ok {
+		return fmt.Errorf("Pin %v is not opened", pin)
+	}
+	d.levels[pin] = state
+	return nil
+}
+
+type simulatedPin struct {
+	pin    domain.Pin
+	driver *SimulatedDriver
+}
+
+func (p simulatedPin) ReadState() (bool, error) {
+	return p.driver.GetState(p.pin)
+}
+
+func (p simulatedPin) WriteState(state bool) error {
+	return p.driver.write(p.pin, state)
+}
+
+func (p simulatedPin) Watch(handler func(state bool)) (func(), error) {
+	return p.driver.watch(p.pin, handler), nil
+}
+
+func (p simulatedPin) String() string {
+	return fmt.Sprintf("sim(%v)", p.pin)
+}
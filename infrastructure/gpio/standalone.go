@@ -0,0 +1,217 @@
+package gpio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+)
+
+// InputOptions tune a standalone input pin.
+type InputOptions struct {
+	Debounce time.Duration
+	Polarity enum.Polarity
+	Pull     enum.Pull
+	// OnGesture reports the gestures of a push button, besides the changes passed to the handler.
+	OnGesture   func(enum.Gesture)
+	DoublePress bool
+	// Owner names the device using the pin in the diagnostics.
+	Owner string
+}
+
+// OutputOptions tune a standalone output pin.
+type OutputOptions struct {
+	Polarity enum.Polarity
+	Owner    string
+}
+
+// standaloneInput reports the changes of a pin which is not connected to any output, like a door contact.
+type standaloneInput struct {
+	handler  func(state bool)
+	gestures *gestureRecognizer
+	cancel   context.CancelFunc
+
+	// written by the worker under mux
+	mux   sync.Mutex
+	state bool
+}
+
+// standaloneOutput is a pin driven only through SetOutputState, like a socket.
+type standaloneOutput struct {
+	pin   DriverPin
+	state bool
+}
+
+// RegisterInputPin starts watching the pin, the handler is called with every debounced change of its state.
+func (s *Service) RegisterInputPin(pin domain.Pin, opts InputOptions, handler func(state bool)) error {
+	if !s.isActive() {
+		return inactiveErr
+	}
+	if handler == nil {
+		return errors.New("Input pin requires a handler")
+	}
+	if err := s.IsPinRegistered(pin); err != nil {
+		return err
+	}
+
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	wi, err := s.openPin(pin, enum.PinModeInput, opts.Pull, opts.Polarity, enum.PinRoleInput, opts.Owner)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	i := &standaloneInput{
+		handler:  handler,
+		gestures: createGestureRecognizer(PairOptions{OnGesture: opts.OnGesture, DoublePress: opts.DoublePress}),
+		cancel:   cancel,
+		state:    defaultPinState,
+	}
+	s.workers.Add(1)
+	go s.inputWorker(ctx, s.watchInput(pin, wi, opts.Debounce), i)
+	s.inputPins[pin] = i
+	return nil
+}
+
+func (s *Service) UnregisterInputPin(pin domain.Pin) error {
+	if !s.isActive() {
+		return inactiveErr
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	return s.internalUnregisterInputPin(pin)
+}
+
+func (s *Service) GetInputState(pin domain.Pin) (bool, error) {
+	if !s.isActive() {
+		return defaultPinState, inactiveErr
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	i, ok := s.inputPins[pin]
+	if !ok {
+		return defaultPinState, fmt.Errorf("Pin %v is not registered as standalone input pin", pin)
+	}
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	return i.state, nil
+}
+
+// RegisterOutputPin opens the pin as an output in the inactive state.
+func (s *Service) RegisterOutputPin(pin domain.Pin, opts OutputOptions) error {
+	if !s.isActive() {
+		return inactiveErr
+	}
+	if err := s.IsPinRegistered(pin); err != nil {
+		return err
+	}
+
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	wo, err := s.openPin(pin, enum.PinModeOutput, enum.PullNone, opts.Polarity, enum.PinRoleOutput, opts.Owner)
+	if err != nil {
+		return err
+	}
+	if err := wo.WriteState(defaultPinState); err != nil {
+		s.releasePins(pin)
+		return err
+	}
+	s.outputPins[pin] = &standaloneOutput{
+		pin:   wo,
+		state: defaultPinState,
+	}
+	return nil
+}
+
+func (s *Service) UnregisterOutputPin(pin domain.Pin) error {
+	if !s.isActive() {
+		return inactiveErr
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	return s.internalUnregisterOutputPin(pin)
+}
+
+func (s *Service) SetOutputState(pin domain.Pin, state bool) error {
+	if !s.isActive() {
+		return inactiveErr
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	o, ok := s.outputPins[pin]
+	if !ok {
+		return fmt.Errorf("Pin %v is not registered as standalone output pin", pin)
+	}
+	if err := o.pin.WriteState(state); err != nil {
+		s.events.error(pin, err)
+		return err
+	}
+	if o.state != state {
+		o.state = state
+		s.events.output(pin, state)
+	}
+	return nil
+}
+
+func (s *Service) GetOutputState(pin domain.Pin) (bool, error) {
+	if !s.isActive() {
+		return defaultPinState, inactiveErr
+	}
+	s.pinMux.Lock()
+	defer s.pinMux.Unlock()
+	o, ok := s.outputPins[pin]
+	if !ok {
+		return defaultPinState, fmt.Errorf("Pin %v is not registered as standalone output pin", pin)
+	}
+	return o.state, nil
+}
+
+func (s *Service) internalUnregisterInputPin(pin domain.Pin) error {
+	i, ok := s.inputPins[pin]
+	if !ok {
+		return fmt.Errorf("Pin %v is not registered as standalone input pin", pin)
+	}
+	i.cancel()
+	if i.gestures != nil {
+		i.gestures.stop()
+	}
+	delete(s.inputPins, pin)
+	s.releasePins(pin)
+	return nil
+}
+
+func (s *Service) internalUnregisterOutputPin(pin domain.Pin) error {
+	o, ok := s.outputPins[pin]
+	if !ok {
+		return fmt.Errorf("Pin %v is not registered as standalone output pin", pin)
+	}
+	delete(s.outputPins, pin)
+	s.releasePins(pin)
+	return o.pin.WriteState(defaultPinState)
+}
+
+// inputWorker calls the handler with the changes of the input. It is not waited for when unregistered,
+// as the handler may be calling the service.
+func (s *Service) inputWorker(ctx context.Context, in *input, i *standaloneInput) {
+	defer s.workers.Done()
+	defer in.stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case v := <-in.events:
+			i.mux.Lock()
+			i.state = v
+			i.mux.Unlock()
+			i.handler(v)
+			if i.gestures != nil {
+				i.gestures.edge(v)
+			}
+		}
+	}
+}
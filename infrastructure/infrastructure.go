@@ -1,49 +1,53 @@
 package infrastructure
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/Erexo/Ventana/api"
+	"github.com/Erexo/Ventana/infrastructure/config"
 	"github.com/Erexo/Ventana/infrastructure/gpio"
+	"github.com/Erexo/Ventana/infrastructure/inventory"
 	"github.com/Erexo/Ventana/infrastructure/light"
 	"github.com/Erexo/Ventana/infrastructure/sunblind"
 	"github.com/Erexo/Ventana/infrastructure/thermal"
 	"github.com/Erexo/Ventana/infrastructure/user"
 )
 
-var (
-	gs *gpio.Service
-	us *user.Service
-	ss *sunblind.Service
-	ts *thermal.Service
-)
+// how long the running requests and workers are waited for once the application is terminated
+const shutdownTimeout = 10 * time.Second
 
-func Run() {
-	gs = gpio.CreateService()
-	us = user.CreateService()
-	ss = sunblind.CreateService(gs)
-	ls := light.CreateService(gs)
-	if err := ss.Load(); err != nil {
-		log.Println("SunblindService error:", err)
-	} else {
-		log.Println("Loaded sunblind service")
+// Run starts the services and the API, and stops them once the context is done.
+func Run(ctx context.Context) error {
+	cfg := config.GetConfig()
+	driver, err := gpio.CreateDriver(cfg)
+	if err != nil {
+		return fmt.Errorf("GpioService error: %w", err)
 	}
-	ts = thermal.CreateService()
-	if err := ts.Load(); err != nil {
-		log.Println("ThermalService error:", err)
-	} else {
-		log.Println("Loaded thermal service")
+	is := inventory.CreateService()
+	gs, err := gpio.CreateService(driver, cfg.GpioInputMode, is)
+	if err != nil {
+		return fmt.Errorf("GpioService error: %w", err)
 	}
+	us := user.CreateService()
+	ss := sunblind.CreateService(gs, is)
+	ls := light.CreateService(gs, is)
+	ts := thermal.CreateService()
 
+	o := CreateOrchestrator()
+	o.Add("GpioService", gs)
+	o.Add("SunblindService", ss, "GpioService")
+	o.Add("LightService", ls, "GpioService")
+	o.Add("ThermalService", ts)
 	// todo, add flag to run api
-	if err := api.Run(us, ts, ss, ls); err != nil {
-		log.Println("Api error:", err)
-	}
-}
+	o.Add("Api", api.CreateServer(us, ts, ss, ls, gs, is))
+	o.Start(ctx)
 
-func Terminate() error {
-	if gs == nil {
-		return nil
-	}
-	return gs.Close()
+	<-ctx.Done()
+	log.Println("Shutting down")
+	stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return o.Stop(stopCtx)
 }
@@ -0,0 +1,45 @@
+package inventory
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Erexo/Ventana/api/controller"
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/go-chi/chi"
+)
+
+type Controller struct {
+	s *Service
+}
+
+func CreateController(s *Service) *Controller {
+	return &Controller{
+		s: s,
+	}
+}
+
+func (c *Controller) GetPrefix() string {
+	return "/inventory"
+}
+
+func (c *Controller) Route(r chi.Router) {
+	r.Use(controller.RequireRole(domain.RoleAdmin))
+	r.Get("/", c.browse)
+}
+
+// @Router /api/inventory [get]
+// @Success 200 {array} dto.PinAllocation
+// @Produce  json
+// @Security ApiKeyAuth
+func (c *Controller) browse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+	ret, err := c.s.Browse()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	retj, _ := json.Marshal(ret)
+	w.WriteHeader(http.StatusOK)
+	w.Write(retj)
+}
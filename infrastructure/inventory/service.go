@@ -0,0 +1,92 @@
+package inventory
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/dto"
+	"github.com/Erexo/Ventana/core/enum"
+	"github.com/Erexo/Ventana/infrastructure/db"
+	"github.com/georgysavva/scany/sqlscan"
+)
+
+// Claim is a pin a device is about to use.
+type Claim struct {
+	Pin  domain.Pin
+	Role enum.PinRole
+}
+
+// Service keeps the pins claimed by the devices, including those failing to register, so that
+// no pin is assigned twice. The claims are changed within the transactions changing the devices.
+type Service struct {
+}
+
+func CreateService() *Service {
+	return &Service{}
+}
+
+// Browse lists the allocation of the pins, ordered by pin.
+func (s *Service) Browse() ([]dto.PinAllocation, error) {
+	var ret []dto.PinAllocation
+	if err := db.Select(&ret, "SELECT pin, role, device, deviceid, label FROM pin ORDER BY id ASC"); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Pin.Less(ret[j].Pin)
+	})
+	return ret, nil
+}
+
+// Claim records the pins of the device, labelled with its name. A pin claimed before is refused,
+// unless it is an input bound to several devices. Unset pins are skipped.
+func (s *Service) Claim(tx *sql.Tx, device enum.Device, deviceId int64, label string, claims ...Claim) error {
+	for _, c := range claims {
+		if !c.Pin.IsSet() {
+			continue
+		}
+		var taken []dto.PinAllocation
+		if err := sqlscan.Select(db.Ctx(), tx, &taken, "SELECT pin, role, device, deviceid, label FROM pin WHERE pin=?", c.Pin); db.IsError(err) {
+			return err
+		}
+		for _, t := range taken {
+			shared := t.Role == enum.PinRoleBoundInput && c.Role == enum.PinRoleBoundInput
+			if !shared || (t.Device == device && t.DeviceId == deviceId) {
+				return fmt.Errorf("Pin %v is already used by %s '%s'", c.Pin, t.Device, t.Label)
+			}
+		}
+		if _, err := tx.Exec("INSERT INTO pin (pin, role, device, deviceid, label) VALUES (?, ?, ?, ?, ?)", c.Pin, c.Role, device, deviceId, label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckUnclaimed refuses the pins claimed by a device, whether the device is registered or failed to register.
+func (s *Service) CheckUnclaimed(pins ...domain.Pin) error {
+	for _, pin := range pins {
+		var taken []dto.PinAllocation
+		if err := db.Select(&taken, "SELECT pin, role, device, deviceid, label FROM pin WHERE pin=?", pin); err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			return fmt.Errorf("Pin %v is already used by %s '%s'", pin, taken[0].Device, taken[0].Label)
+		}
+	}
+	return nil
+}
+
+// Release frees the given pins of the device, or all of them when none are given.
+func (s *Service) Release(tx *sql.Tx, device enum.Device, deviceId int64, pins ...domain.Pin) error {
+	if len(pins) == 0 {
+		_, err := tx.Exec("DELETE FROM pin WHERE device=? AND deviceid=?", device, deviceId)
+		return err
+	}
+	for _, pin := range pins {
+		if _, err := tx.Exec("DELETE FROM pin WHERE device=? AND deviceid=? AND pin=?", device, deviceId, pin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
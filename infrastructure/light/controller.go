@@ -7,6 +7,7 @@ import (
 
 	"github.com/Erexo/Ventana/api/controller"
 	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
 	"github.com/go-chi/chi"
 )
 
@@ -31,6 +32,9 @@ func (c *Controller) Route(r chi.Router) {
 	r.Patch("/update/{id}", c.update)
 	r.Delete("/delete/{id}", c.delete)
 	r.Post("/toggle/{id}", c.toggle)
+	r.Post("/brightness/{id}", c.setBrightness)
+	r.Post("/input/{id}", c.attachInput)
+	r.Delete("/input/{id}/{pin}", c.detachInput)
 }
 
 // @Router /api/light/order [post]
@@ -92,7 +96,7 @@ func (c *Controller) create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if err := c.s.Create(d.Name, d.InputPin, d.OutputPin); err != nil {
+	if err := c.s.Create(d.Name, d.InputPin, d.OutputPin, d.OutputPolarity, d.RestorePolicy, d.RelaySettings, d.InputSettings); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -116,7 +120,7 @@ func (c *Controller) update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if err := c.s.Update(id, d.Name, d.InputPin, d.OutputPin); err != nil {
+	if err := c.s.Update(id, d.Name, d.InputPin, d.OutputPin, d.OutputPolarity, d.RestorePolicy, d.RelaySettings, d.InputSettings); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -156,8 +160,89 @@ func (c *Controller) toggle(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(strconv.FormatBool(state)))
 }
 
+// @Router /api/light/brightness/{id} [post]
+// @Param id path int true "path"
+// @Param body body brightnessDto true "body"
+// @Success 200 {string} plain
+// @Accept  json
+// @Produce  plain
+// @Security ApiKeyAuth
+func (c *Controller) setBrightness(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var d brightnessDto
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.SetBrightness(id, d.Brightness); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// @Router /api/light/input/{id} [post]
+// @Param id path int true "path"
+// @Param body body inputDto true "body"
+// @Success 200 {string} plain
+// @Accept  json
+// @Produce  plain
+// @Security ApiKeyAuth
+func (c *Controller) attachInput(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var d inputDto
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.AttachInput(id, d.InputPin); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// @Router /api/light/input/{id}/{pin} [delete]
+// @Param id path int true "path"
+// @Param pin path string true "path"
+// @Success 200 {string} plain
+// @Security ApiKeyAuth
+func (c *Controller) detachInput(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pin, err := domain.ParsePin(chi.URLParam(r, "pin"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.DetachInput(id, pin); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 type saveDto struct {
-	Name      string     `json:"name"`
-	InputPin  domain.Pin `json:"inputpin"`
-	OutputPin domain.Pin `json:"outputpin"`
+	Name           string             `json:"name"`
+	InputPin       domain.Pin         `json:"inputpin" swaggertype:"string"`
+	OutputPin      domain.Pin         `json:"outputpin" swaggertype:"string"`
+	OutputPolarity enum.Polarity      `json:"outputpolarity"`
+	RestorePolicy  enum.RestorePolicy `json:"restorepolicy"`
+	domain.RelaySettings
+	domain.InputSettings
+}
+
+type inputDto struct {
+	InputPin domain.Pin `json:"inputpin" swaggertype:"string"`
+}
+
+type brightnessDto struct {
+	Brightness uint8 `json:"brightness"`
 }
@@ -1,27 +1,35 @@
 package light
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/Erexo/Ventana/core/domain"
 	"github.com/Erexo/Ventana/core/dto"
 	"github.com/Erexo/Ventana/core/entity"
 	"github.com/Erexo/Ventana/core/enum"
+	"github.com/Erexo/Ventana/core/utils"
 	"github.com/Erexo/Ventana/infrastructure/db"
 	"github.com/Erexo/Ventana/infrastructure/gpio"
+	"github.com/Erexo/Ventana/infrastructure/inventory"
 	"github.com/georgysavva/scany/sqlscan"
 )
 
 type Service struct {
 	gs *gpio.Service
+	is *inventory.Service
+	// saver is started by Start, the pair workers report the states of the lights to it
+	saver *stateSaver
 }
 
-func CreateService(pm *gpio.Service) *Service {
+func CreateService(pm *gpio.Service, is *inventory.Service) *Service {
 	return &Service{
 		gs: pm,
+		is: is,
 	}
 }
 
@@ -71,7 +79,7 @@ func (s *Service) SaveOrder(userId int64, order []int64) error {
 
 func (s *Service) Browse(userId int64) ([]*dto.Light, error) {
 	var lights []*entity.Light
-	err := db.Select(&lights, "SELECT id, name, inputpin, outputpin FROM light ORDER BY id ASC")
+	err := db.Select(&lights, "SELECT id, name, inputpin, outputpin, outputpolarity, restorepolicy, pairtype, feedbackpin, pulseduration, fadeduration, inputpolarity, inputpull, debounce, pressaction, longpressaction, doublepressaction, releaseaction FROM light ORDER BY id ASC")
 	if err != nil {
 		return nil, err
 	}
@@ -79,12 +87,16 @@ func (s *Service) Browse(userId int64) ([]*dto.Light, error) {
 	if err := db.Select(&order, "SELECT lightid FROM lightorder WHERE userid=? ORDER BY id ASC", userId); err != nil {
 		return nil, err
 	}
+	var inputs []inputData
+	if err := db.Select(&inputs, "SELECT lightid, inputpin FROM lightinput ORDER BY id ASC"); err != nil {
+		return nil, err
+	}
 	ret := make([]*dto.Light, len(lights))
 	i := 0
 	for _, id := range order {
 		for j, light := range lights {
 			if light != nil && light.Id == id {
-				ret[i] = s.getLight(light)
+				ret[i] = s.getLight(light, inputs)
 				i++
 				lights[j] = nil
 				break
@@ -93,28 +105,64 @@ func (s *Service) Browse(userId int64) ([]*dto.Light, error) {
 	}
 	for _, light := range lights {
 		if light != nil {
-			ret[i] = s.getLight(light)
+			ret[i] = s.getLight(light, inputs)
 			i++
 		}
 	}
 	return ret, nil
 }
 
-func (s *Service) Create(name string, inputPin, outputPin domain.Pin) error {
+func (s *Service) Create(name string, inputPin, outputPin domain.Pin, outputPolarity enum.Polarity, restorePolicy enum.RestorePolicy, relay domain.RelaySettings, settings domain.InputSettings) error {
 	if err := entity.ValidateName(&name); err != nil {
 		return fmt.Errorf("Name: %w", err)
 	}
-	if err := s.gs.IsPinRegistered(inputPin, outputPin); err != nil {
+	if err := validatePins(inputPin, outputPin, relay.PairType); err != nil {
+		return err
+	}
+	if !outputPolarity.IsValid() {
+		return errors.New("Invalid output polarity")
+	}
+	if !restorePolicy.IsValid() {
+		return errors.New("Invalid restore policy")
+	}
+	if err := relay.Validate(); err != nil {
 		return err
 	}
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	if err := s.gs.IsPinRegistered(append([]domain.Pin{inputPin, outputPin}, relay.GetPins()...)...); err != nil {
+		return err
+	}
+	initialState := restorePolicy.GetState(false)
 
-	r, err := db.Exec("INSERT INTO light (name, inputpin, outputpin) VALUES (?, ?, ?)", name, inputPin, outputPin)
+	tx, close, err := db.GetTransaction()
+	if err != nil {
+		return err
+	}
+	defer close()
+	r, err := tx.Exec("INSERT INTO light (name, inputpin, outputpin, outputpolarity, restorepolicy, laststate, pairtype, feedbackpin, pulseduration, fadeduration, inputpolarity, inputpull, debounce, pressaction, longpressaction, doublepressaction, releaseaction) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		name, inputPin, outputPin, outputPolarity, restorePolicy, initialState, relay.PairType, relay.FeedbackPin, relay.PulseDuration, relay.FadeDuration, settings.InputPolarity, settings.InputPull, settings.Debounce, settings.PressAction, settings.LongPressAction, settings.DoublePressAction, settings.ReleaseAction)
 	if err != nil {
 		return err
 	}
 	id, _ := r.LastInsertId()
 
-	if err := s.gs.RegisterPinPair(inputPin, outputPin, enum.PairTypeToggle); err != nil {
+	light := loadData{
+		Id:             id,
+		InputPin:       inputPin,
+		OutputPin:      outputPin,
+		OutputPolarity: outputPolarity,
+		RelaySettings:  relay,
+		InputSettings:  settings,
+	}
+	if err := s.is.Claim(tx, enum.DeviceLight, id, name, getClaims(light, nil)...); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if err := s.register(light, initialState, 0); err != nil {
 		return err
 	}
 
@@ -122,17 +170,36 @@ func (s *Service) Create(name string, inputPin, outputPin domain.Pin) error {
 	return nil
 }
 
-func (s *Service) Update(id int64, name string, inputPin, outputPin domain.Pin) error {
+func (s *Service) Update(id int64, name string, inputPin, outputPin domain.Pin, outputPolarity enum.Polarity, restorePolicy enum.RestorePolicy, relay domain.RelaySettings, settings domain.InputSettings) error {
 	if err := entity.ValidateName(&name); err != nil {
 		return fmt.Errorf("Name: %w", err)
 	}
+	if err := validatePins(inputPin, outputPin, relay.PairType); err != nil {
+		return err
+	}
+	if !outputPolarity.IsValid() {
+		return errors.New("Invalid output polarity")
+	}
+	if !restorePolicy.IsValid() {
+		return errors.New("Invalid restore policy")
+	}
+	if err := relay.Validate(); err != nil {
+		return err
+	}
+	if err := settings.Validate(); err != nil {
+		return err
+	}
 
 	light, err := getData(id)
 	if err != nil {
 		return err
 	}
+	inputs, err := getInputPins(id)
+	if err != nil {
+		return err
+	}
 	var newPins []domain.Pin
-	for _, p := range []domain.Pin{inputPin, outputPin} {
+	for _, p := range append([]domain.Pin{inputPin, outputPin}, relay.GetPins()...) {
 		if !light.ContainsPin(p) {
 			newPins = append(newPins, p)
 		}
@@ -143,15 +210,46 @@ func (s *Service) Update(id int64, name string, inputPin, outputPin domain.Pin)
 		}
 	}
 
-	if _, err := db.Exec("UPDATE light SET name=?, inputpin=?, outputpin=? WHERE id=?", name, inputPin, outputPin, id); err != nil {
+	updated := loadData{
+		Id:             id,
+		InputPin:       inputPin,
+		OutputPin:      outputPin,
+		OutputPolarity: outputPolarity,
+		RelaySettings:  relay,
+		InputSettings:  settings,
+	}
+	tx, close, err := db.GetTransaction()
+	if err != nil {
 		return err
 	}
-
-	if len(newPins) > 0 {
+	defer close()
+	if _, err := tx.Exec("UPDATE light SET name=?, inputpin=?, outputpin=?, outputpolarity=?, restorepolicy=?, pairtype=?, feedbackpin=?, pulseduration=?, fadeduration=?, inputpolarity=?, inputpull=?, debounce=?, pressaction=?, longpressaction=?, doublepressaction=?, releaseaction=? WHERE id=?",
+		name, inputPin, outputPin, outputPolarity, restorePolicy, relay.PairType, relay.FeedbackPin, relay.PulseDuration, relay.FadeDuration, settings.InputPolarity, settings.InputPull, settings.Debounce, settings.PressAction, settings.LongPressAction, settings.DoublePressAction, settings.ReleaseAction, id); err != nil {
+		return err
+	}
+	if err := s.is.Release(tx, enum.DeviceLight, id); err != nil {
+		return err
+	}
+	if err := s.is.Claim(tx, enum.DeviceLight, id, name, getClaims(updated, inputs)...); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if updated != light {
+		// the light keeps its state and brightness through the re-registration
+		state := s.gs.GetPinState(light.InputPin)
+		level := s.gs.GetPinLevel(light.InputPin)
+		if err := s.unbind(light, inputs); err != nil {
+			return err
+		}
 		if err := s.gs.UnregisterPinPair(light.InputPin, light.OutputPin); err != nil {
 			return err
 		}
-		if err := s.gs.RegisterPinPair(inputPin, outputPin, enum.PairTypeToggle); err != nil {
+		if err := s.register(updated, state, level); err != nil {
+			return err
+		}
+		if err := s.bind(updated, inputs); err != nil {
 			return err
 		}
 	}
@@ -165,10 +263,28 @@ func (s *Service) Delete(id int64) error {
 	if err != nil {
 		return err
 	}
-	if _, err := db.Exec("DELETE FROM light WHERE id=?", id); err != nil {
+	inputs, err := getInputPins(id)
+	if err != nil {
+		return err
+	}
+	tx, close, err := db.GetTransaction()
+	if err != nil {
+		return err
+	}
+	defer close()
+	if _, err := tx.Exec("DELETE FROM light WHERE id=?", id); err != nil {
+		return err
+	}
+	if err := s.is.Release(tx, enum.DeviceLight, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
 		return err
 	}
 
+	if err := s.unbind(light, inputs); err != nil {
+		return err
+	}
 	if err := s.gs.UnregisterPinPair(light.InputPin, light.OutputPin); err != nil {
 		return err
 	}
@@ -177,44 +293,160 @@ func (s *Service) Delete(id int64) error {
 	return nil
 }
 
+// AttachInput binds an additional wall switch to the light, wired and interpreted like its own input.
+func (s *Service) AttachInput(id int64, inputPin domain.Pin) error {
+	light, err := getData(id)
+	if err != nil {
+		return err
+	}
+	if light.ContainsPin(inputPin) {
+		return fmt.Errorf("Pin %v is already used by Light '%d'", inputPin, id)
+	}
+	if err := s.gs.CanBindInputPin(inputPin, light.InputPin); err != nil {
+		return err
+	}
+
+	var name string
+	if err := db.Get(&name, "SELECT name FROM light WHERE id=?", id); err != nil {
+		return err
+	}
+	tx, close, err := db.GetTransaction()
+	if err != nil {
+		return err
+	}
+	defer close()
+	if _, err := tx.Exec("INSERT INTO lightinput (lightid, inputpin) VALUES (?, ?)", id, inputPin); err != nil {
+		return err
+	}
+	if err := s.is.Claim(tx, enum.DeviceLight, id, name, inventory.Claim{Pin: inputPin, Role: enum.PinRoleBoundInput}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := s.bind(light, []domain.Pin{inputPin}); err != nil {
+		return err
+	}
+
+	log.Printf("Attached input pin %v to Light '%d'\n", inputPin, id)
+	return nil
+}
+
+func (s *Service) DetachInput(id int64, inputPin domain.Pin) error {
+	light, err := getData(id)
+	if err != nil {
+		return err
+	}
+	tx, close, err := db.GetTransaction()
+	if err != nil {
+		return err
+	}
+	defer close()
+	r, err := tx.Exec("DELETE FROM lightinput WHERE lightid=? AND inputpin=?", id, inputPin)
+	if err != nil {
+		return err
+	}
+	if n, _ := r.RowsAffected(); n == 0 {
+		return fmt.Errorf("Pin %v is not attached to Light '%d'", inputPin, id)
+	}
+	if err := s.is.Release(tx, enum.DeviceLight, id, inputPin); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := s.unbind(light, []domain.Pin{inputPin}); err != nil {
+		return err
+	}
+
+	log.Printf("Detached input pin %v from Light '%d'\n", inputPin, id)
+	return nil
+}
+
 func (s *Service) Toggle(id int64) (error, bool) {
-	var pin domain.Pin
-	if err := db.Get(&pin, "SELECT inputpin FROM light WHERE id=?", id); err != nil {
+	var light loadData
+	if err := db.Get(&light, "SELECT inputpin FROM light WHERE id=?", id); err != nil {
 		return err, false
 	}
-	err := s.gs.TogglePin(pin)
-	state := s.gs.GetPinState(pin)
+	err := s.gs.TogglePin(light.InputPin)
+	state := s.gs.GetPinState(light.InputPin)
 	return err, state
 }
 
-func (s *Service) Load() error {
-	var lights []*loadData
-	err := db.Select(&lights, "SELECT inputpin, outputpin FROM light")
+// SetBrightness sets the brightness of a dimmable light in percent, 0 switches it off.
+func (s *Service) SetBrightness(id int64, brightness uint8) error {
+	if brightness > 100 {
+		return errors.New("Brightness must be between 0 and 100")
+	}
+	light, err := getData(id)
 	if err != nil {
 		return err
 	}
+	if light.PairType != enum.PairTypeDimmable {
+		return fmt.Errorf("Light '%d' is not dimmable", id)
+	}
+	return s.gs.SetPinLevel(light.InputPin, brightness)
+}
+
+// Start registers the lights in the state their restore policy gives. A light failing to register is logged
+// and left out, so the others are still controlled.
+func (s *Service) Start(ctx context.Context) error {
+	var lights []*struct {
+		RestorePolicy enum.RestorePolicy `db:"restorepolicy"`
+		LastState     bool               `db:"laststate"`
+		Brightness    uint8              `db:"brightness"`
+		loadData
+	}
+	err := db.Select(&lights, "SELECT id, restorepolicy, laststate, brightness, inputpin, outputpin, outputpolarity, pairtype, feedbackpin, pulseduration, fadeduration, inputpolarity, inputpull, debounce, pressaction, longpressaction, doublepressaction, releaseaction FROM light")
+	if err != nil {
+		return err
+	}
+	var inputs []inputData
+	if err := db.Select(&inputs, "SELECT lightid, inputpin FROM lightinput ORDER BY id ASC"); err != nil {
+		return err
+	}
+	s.saver = createStateSaver()
+	go s.saver.run()
+	registered := lights[:0]
 	for _, light := range lights {
-		if err := s.gs.RegisterPinPair(light.InputPin, light.OutputPin, enum.PairTypeToggle); err != nil {
-			return err
+		if err := s.register(light.loadData, light.RestorePolicy.GetState(light.LastState), light.Brightness); err != nil {
+			log.Printf("Registering Light '%d' failed: %v\n", light.Id, err)
+			continue
+		}
+		registered = append(registered, light)
+	}
+	for _, light := range registered {
+		if err := s.bind(light.loadData, filterInputPins(inputs, light.Id)); err != nil {
+			log.Printf("Binding the inputs of Light '%d' failed: %v\n", light.Id, err)
 		}
 	}
 	return nil
 }
 
-func (s *Service) getLight(light *entity.Light) *dto.Light {
+func (s *Service) getLight(light *entity.Light, inputs []inputData) *dto.Light {
 	ret := dto.Light{
-		Id:        light.Id,
-		Name:      light.Name,
-		InputPin:  light.InputPin,
-		OutputPin: light.OutputPin,
+		Id:             light.Id,
+		Name:           light.Name,
+		InputPin:       light.InputPin,
+		OutputPin:      light.OutputPin,
+		OutputPolarity: light.OutputPolarity,
+		RestorePolicy:  light.RestorePolicy,
+		RelaySettings:  light.RelaySettings,
+		InputSettings:  light.InputSettings,
+		ExtraInputPins: filterInputPins(inputs, light.Id),
 	}
 	ret.State = s.gs.GetPinState(ret.InputPin)
+	ret.Brightness = s.gs.GetPinLevel(ret.InputPin)
+	pins := append([]domain.Pin{ret.InputPin, ret.OutputPin}, ret.GetPins()...)
+	ret.Degraded = s.gs.IsDegraded(append(pins, ret.ExtraInputPins...)...)
 	return &ret
 }
 
 func getData(id int64) (loadData, error) {
 	var light loadData
-	if err := db.Get(&light, "SELECT inputpin, outputpin FROM light WHERE id=?", id); err != nil {
+	if err := db.Get(&light, "SELECT id, inputpin, outputpin, outputpolarity, pairtype, feedbackpin, pulseduration, fadeduration, inputpolarity, inputpull, debounce, pressaction, longpressaction, doublepressaction, releaseaction FROM light WHERE id=?", id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return loadData{}, fmt.Errorf("Light '%d' does not exist", id)
 		}
@@ -223,9 +455,138 @@ func getData(id int64) (loadData, error) {
 	return light, nil
 }
 
+func getInputPins(id int64) ([]domain.Pin, error) {
+	var inputs []inputData
+	if err := db.Select(&inputs, "SELECT lightid, inputpin FROM lightinput WHERE lightid=? ORDER BY id ASC", id); err != nil {
+		return nil, err
+	}
+	return filterInputPins(inputs, id), nil
+}
+
+// getClaims lists the pins of the light for the inventory, with its additional inputs.
+func getClaims(light loadData, inputPins []domain.Pin) []inventory.Claim {
+	claims := []inventory.Claim{
+		{Pin: light.InputPin, Role: enum.PinRoleInput},
+		{Pin: light.OutputPin, Role: enum.PinRoleOutput},
+	}
+	for _, pin := range light.GetPins() {
+		claims = append(claims, inventory.Claim{Pin: pin, Role: enum.PinRoleFeedback})
+	}
+	for _, pin := range inputPins {
+		claims = append(claims, inventory.Claim{Pin: pin, Role: enum.PinRoleBoundInput})
+	}
+	return claims
+}
+
+// validatePins checks the pins the light is addressed and driven by, the gpio service keys its pair by the input pin.
+func validatePins(inputPin, outputPin domain.Pin, pairType enum.PairType) error {
+	if !inputPin.IsSet() || !outputPin.IsSet() {
+		return errors.New("Light requires both an input and an output pin")
+	}
+	if pairType == enum.PairTypeDimmable && outputPin.IsExpanderPin() {
+		return errors.New("Dimmable light requires a native output pin, rpio 12, 13, 18 or 19 for the hardware PWM")
+	}
+	return nil
+}
+
+func filterInputPins(inputs []inputData, id int64) []domain.Pin {
+	pins := []domain.Pin{}
+	for _, input := range inputs {
+		if input.LightId == id {
+			pins = append(pins, input.InputPin)
+		}
+	}
+	return pins
+}
+
+func (s *Service) register(light loadData, state bool, level uint8) error {
+	opts := s.pairOptions(light)
+	opts.InitialState = state
+	opts.InitialLevel = level
+	return s.gs.RegisterPinPair(light.InputPin, light.OutputPin, light.PairType, opts)
+}
+
+// Stop ends saving the states before the gpio service resets the outputs, so the lights are restored as they were.
+func (s *Service) Stop(ctx context.Context) error {
+	if s.saver == nil {
+		return nil
+	}
+	if err := s.saver.close(ctx); err != nil {
+		return fmt.Errorf("Light service did not stop in time: %w", err)
+	}
+	return nil
+}
+
+// bind attaches the additional inputs, the gestures of each of them perform the actions of the light.
+func (s *Service) bind(light loadData, inputPins []domain.Pin) error {
+	var ret error
+	for _, pin := range inputPins {
+		ret = utils.ConcatErrors(ret, s.gs.BindInputPin(pin, light.InputPin, s.pairOptions(light)))
+	}
+	return ret
+}
+
+func (s *Service) unbind(light loadData, inputPins []domain.Pin) error {
+	var ret error
+	for _, pin := range inputPins {
+		ret = utils.ConcatErrors(ret, s.gs.UnbindInputPin(pin, light.InputPin))
+	}
+	return ret
+}
+
+func (s *Service) pairOptions(light loadData) gpio.PairOptions {
+	opts := gpio.CreatePairOptions(light.PulseDuration.Duration(), light.InputSettings, func(action enum.Action) {
+		if err := s.perform(light.InputPin, action); err != nil {
+			log.Printf("Light action %d on pin %v failed: %v\n", action, light.InputPin, err)
+		}
+	})
+	opts.OutputPolarity = light.OutputPolarity
+	opts.FeedbackPin = light.FeedbackPin
+	opts.FadeDuration = light.FadeDuration.Duration()
+	// a held wall switch dims the light, unless the long press has an action of its own
+	opts.HoldToDim = light.PairType == enum.PairTypeDimmable && light.LongPressAction == enum.ActionNone
+	opts.Owner = fmt.Sprintf("Light '%d'", light.Id)
+	if s.saver != nil {
+		opts.OnOutput = func(state bool, _ time.Time) { s.saver.saveState(light.Id, state) }
+		opts.OnLevel = func(level uint8) { s.saver.saveLevel(light.Id, level) }
+	}
+	return opts
+}
+
+func (s *Service) perform(inputPin domain.Pin, action enum.Action) error {
+	switch action {
+	case enum.ActionToggle:
+		return s.gs.TogglePin(inputPin)
+	case enum.ActionOn, enum.ActionOff:
+		return s.gs.SetPinActive(inputPin, action == enum.ActionOn)
+	case enum.ActionHouseOn, enum.ActionHouseOff:
+		// lights are not grouped by room, a whole-house action switches every light of the house
+		var lights []loadData
+		if err := db.Select(&lights, "SELECT inputpin FROM light"); err != nil {
+			return err
+		}
+		var ret error
+		for _, light := range lights {
+			ret = utils.ConcatErrors(ret, s.gs.SetPinActive(light.InputPin, action == enum.ActionHouseOn))
+		}
+		return ret
+	default:
+		return nil
+	}
+}
+
+type inputData struct {
+	LightId  int64      `db:"lightid"`
+	InputPin domain.Pin `db:"inputpin"`
+}
+
 type loadData struct {
-	InputPin  domain.Pin `db:"inputdownpin"`
-	OutputPin domain.Pin `db:"outputdownpin"`
+	Id             int64         `db:"id"`
+	InputPin       domain.Pin    `db:"inputpin"`
+	OutputPin      domain.Pin    `db:"outputpin"`
+	OutputPolarity enum.Polarity `db:"outputpolarity"`
+	domain.RelaySettings
+	domain.InputSettings
 }
 
 func (l loadData) ContainsPin(pin domain.Pin) bool {
@@ -233,7 +594,11 @@ func (l loadData) ContainsPin(pin domain.Pin) bool {
 	case l.InputPin,
 		l.OutputPin:
 		return true
-	default:
-		return false
 	}
+	for _, p := range l.GetPins() {
+		if p == pin {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,112 @@
+package light
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/Erexo/Ventana/infrastructure/db"
+)
+
+// stateSaver keeps the last state and brightness of every light, so they can be restored after a restart or a power loss.
+// The pair workers report the changes without waiting for the database, a light changing faster than it is saved
+// only has its latest state written.
+type stateSaver struct {
+	mux    sync.Mutex
+	states map[int64]bool
+	levels map[int64]uint8
+	// pending wakes the saving goroutine once something is recorded
+	pending chan struct{}
+	stop    chan struct{}
+	// saved is closed once the last pending states are written after stop
+	saved chan struct{}
+}
+
+func createStateSaver() *stateSaver {
+	return &stateSaver{
+		states:  make(map[int64]bool),
+		levels:  make(map[int64]uint8),
+		pending: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		saved:   make(chan struct{}),
+	}
+}
+
+func (s *stateSaver) saveState(id int64, state bool) {
+	s.mux.Lock()
+	s.states[id] = state
+	s.mux.Unlock()
+	s.wake()
+}
+
+func (s *stateSaver) saveLevel(id int64, level uint8) {
+	s.mux.Lock()
+	s.levels[id] = level
+	s.mux.Unlock()
+	s.wake()
+}
+
+func (s *stateSaver) wake() {
+	select {
+	case s.pending <- struct{}{}:
+	default:
+	}
+}
+
+func (s *stateSaver) run() {
+	defer close(s.saved)
+	for {
+		select {
+		case <-s.pending:
+			s.write()
+		case <-s.stop:
+			s.write()
+			return
+		}
+	}
+}
+
+// write saves the recorded states, the failed ones are kept pending unless changed since and retried with the next write.
+func (s *stateSaver) write() {
+	s.mux.Lock()
+	states, levels := s.states, s.levels
+	s.states, s.levels = make(map[int64]bool), make(map[int64]uint8)
+	s.mux.Unlock()
+	for id, state := range states {
+		if _, err := db.Exec("UPDATE light SET laststate=? WHERE id=?", state, id); err != nil {
+			log.Printf("Saving the state of Light '%d' failed: %v\n", id, err)
+			continue
+		}
+		delete(states, id)
+	}
+	for id, level := range levels {
+		if _, err := db.Exec("UPDATE light SET brightness=? WHERE id=?", level, id); err != nil {
+			log.Printf("Saving the brightness of Light '%d' failed: %v\n", id, err)
+			continue
+		}
+		delete(levels, id)
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for id, state := range states {
+		if _, ok := s.states[id]; !ok {
+			s.states[id] = state
+		}
+	}
+	for id, level := range levels {
+		if _, ok := s.levels[id]; !ok {
+			s.levels[id] = level
+		}
+	}
+}
+
+// close writes the pending states and ends the saving, the changes reported afterwards are not saved.
+func (s *stateSaver) close(ctx context.Context) error {
+	close(s.stop)
+	select {
+	case <-s.saved:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
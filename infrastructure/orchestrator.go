@@ -0,0 +1,85 @@
+package infrastructure
+
+import (
+	"context"
+	"log"
+
+	"github.com/Erexo/Ventana/core/utils"
+)
+
+// Component is a service with workers of its own, started and stopped along with the application.
+type Component interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+type namedComponent struct {
+	name      string
+	dependsOn []string
+	Component
+}
+
+// Orchestrator starts the components in the order they are added, so each one comes after those it depends on,
+// and stops them in the reverse order.
+type Orchestrator struct {
+	components []namedComponent
+	started    []namedComponent
+}
+
+func CreateOrchestrator() *Orchestrator {
+	return &Orchestrator{}
+}
+
+// Add appends the component, it is left out when one of the components it depends on does not start.
+func (o *Orchestrator) Add(name string, c Component, dependsOn ...string) {
+	o.components = append(o.components, namedComponent{name: name, dependsOn: dependsOn, Component: c})
+}
+
+// Start starts every component, the others may still be useful when one fails to start. The failing one is stopped
+// right away, undoing what it set up before failing, and those depending on it are left out.
+func (o *Orchestrator) Start(ctx context.Context) {
+	failed := make(map[string]bool)
+	for _, c := range o.components {
+		if dependency := c.failedDependency(failed); dependency != "" {
+			log.Printf("Skipped %s, %s did not start\n", c.name, dependency)
+			failed[c.name] = true
+			continue
+		}
+		if err := c.Start(ctx); err != nil {
+			log.Printf("%s error: %v\n", c.name, err)
+			failed[c.name] = true
+			if err := c.Stop(ctx); err != nil {
+				log.Printf("Stopping %s failed: %v\n", c.name, err)
+			}
+			continue
+		}
+		o.started = append(o.started, c)
+		log.Printf("Started %s\n", c.name)
+	}
+}
+
+// Stop stops the started components in the reverse order. A component that does not stop
+// in time does not hold back the others, which are still stopped with the context that is done.
+func (o *Orchestrator) Stop(ctx context.Context) error {
+	var ret error
+	for i := len(o.started) - 1; i >= 0; i-- {
+		c := o.started[i]
+		if err := c.Stop(ctx); err != nil {
+			log.Printf("Stopping %s failed: %v\n", c.name, err)
+			ret = utils.ConcatErrors(ret, err)
+			continue
+		}
+		log.Printf("Stopped %s\n", c.name)
+	}
+	o.started = nil
+	return ret
+}
+
+func (c namedComponent) failedDependency(failed map[string]bool) string {
+	for _, name := range c.dependsOn {
+		if failed[name] {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package sunblind
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Erexo/Ventana/core/enum"
+	"github.com/Erexo/Ventana/infrastructure/gpio"
+)
+
+// registerButton watches the single button of the sunblind, which moves it through both of its pairs.
+func (s *Service) registerButton(sb loadData) error {
+	if sb.ControlMode != enum.ControlModeSingle {
+		return nil
+	}
+	opts := gpio.InputOptions{
+		Debounce: sb.Debounce.Duration(),
+		Polarity: sb.InputPolarity,
+		Pull:     sb.InputPull,
+		OnGesture: func(g enum.Gesture) {
+			if err := s.press(sb.Id, g); err != nil {
+				log.Printf("Sunblind '%d' button %v failed: %v\n", sb.Id, g, err)
+			}
+		},
+		Owner: fmt.Sprintf("Sunblind '%d'", sb.Id),
+	}
+	return s.gs.RegisterInputPin(sb.InputPin, opts, func(bool) {})
+}
+
+func (s *Service) unregisterButton(sb loadData) error {
+	if sb.ControlMode != enum.ControlModeSingle {
+		return nil
+	}
+	return s.gs.UnregisterInputPin(sb.InputPin)
+}
+
+// press cycles the sunblind up, stop, down, stop. A long press travels all the way, the way it already moves
+// or the other way than it last moved, with the pulse of its pairs when the travel times are not set.
+func (s *Service) press(id int64, g enum.Gesture) error {
+	if g != enum.GesturePress && g != enum.GestureLongPress {
+		return nil
+	}
+	full := g == enum.GestureLongPress
+	sb, err := getData(id)
+	if err != nil {
+		return err
+	}
+	s.mux.Lock()
+	t, ok := s.travels[id]
+	if !ok {
+		s.mux.Unlock()
+		return fmt.Errorf("Sunblind '%d' is not registered", id)
+	}
+	stop, down := t.nextPress()
+	s.mux.Unlock()
+
+	switch {
+	case full:
+		return s.travelToEnd(sb, down)
+	case stop:
+		return s.halt(sb)
+	case down:
+		return s.gs.SetPinActive(sb.downPair(), true)
+	default:
+		return s.gs.SetPinActive(sb.upPair(), true)
+	}
+}
+
+// nextPress tells whether a press stops the motor, or else the way it starts it. A long press goes the way told
+// rather than stopping.
+func (t *travel) nextPress() (bool, bool) {
+	if t.isMoving() {
+		return true, t.down
+	}
+	return false, t.nextDown
+}
@@ -0,0 +1,49 @@
+package sunblind
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPressCycle(t *testing.T) {
+	tr := createTestTravel(0, 50, 0)
+	now := time.Now()
+	expected := []struct {
+		stop bool
+		down bool
+	}{
+		{stop: false, down: false},
+		{stop: true, down: false},
+		{stop: false, down: true},
+		{stop: true, down: true},
+		{stop: false, down: false},
+		{stop: true, down: false},
+	}
+	for i, e := range expected {
+		stop, down := tr.nextPress()
+		if stop != e.stop || down != e.down {
+			t.Fatalf("Press %d: expected stop %v down %v, got stop %v down %v", i+1, e.stop, e.down, stop, down)
+		}
+		now = now.Add(time.Second)
+		if stop {
+			tr.stop(now)
+		} else {
+			tr.start(down, now)
+		}
+	}
+}
+
+func TestPressAfterTravelGoesTheOtherWay(t *testing.T) {
+	tr := createTestTravel(0, 0, 0)
+	now := time.Now()
+	tr.start(true, now)
+	tr.stop(now.Add(time.Minute))
+	if stop, down := tr.nextPress(); stop || down {
+		t.Fatalf("Expected a press to start up after going down, got stop %v down %v", stop, down)
+	}
+	tr.start(false, now.Add(2*time.Minute))
+	tr.stop(now.Add(3 * time.Minute))
+	if stop, down := tr.nextPress(); stop || !down {
+		t.Fatalf("Expected a press to start down after going up, got stop %v down %v", stop, down)
+	}
+}
@@ -2,12 +2,13 @@ package sunblind
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/Erexo/Ventana/api/controller"
 	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
 	"github.com/go-chi/chi"
 )
 
@@ -32,6 +33,19 @@ func (c *Controller) Route(r chi.Router) {
 	r.Patch("/update/{id}", c.update)
 	r.Delete("/delete/{id}", c.delete)
 	r.Post("/toggle/{id}/{dir}", c.toggle)
+	r.Post("/stop/{id}", c.stop)
+	r.Post("/position/{id}", c.position)
+	r.Post("/tilt/{id}", c.tilt)
+	r.Post("/group/browse", c.browseGroups)
+	r.Group(func(r chi.Router) {
+		r.Use(controller.RequireRole(domain.RoleAdmin))
+		r.Post("/group/create", c.createGroup)
+		r.Patch("/group/update/{id}", c.updateGroup)
+		r.Delete("/group/delete/{id}", c.deleteGroup)
+	})
+	r.Post("/group/move/{id}/{dir}", c.moveGroup)
+	r.Post("/group/stop/{id}", c.stopGroup)
+	r.Post("/group/position/{id}", c.positionGroup)
 }
 
 // @Router /api/sunblind/order [post]
@@ -93,7 +107,7 @@ func (c *Controller) create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if err := c.s.Create(d.Name, d.InputDownPin, d.InputUpPin, d.OutputDownPin, d.OutputUpPin); err != nil {
+	if err := c.s.Create(d.Name, d.SunblindType, d.ControlMode, d.InputPin, d.InputDownPin, d.InputUpPin, d.OutputDownPin, d.OutputUpPin, d.PulseDuration, d.DeadTime, d.TravelDownTime, d.TravelUpTime, d.TiltTime, d.OutputPolarity, d.InputSettings); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -117,7 +131,7 @@ func (c *Controller) update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if err := c.s.Update(id, d.Name, d.InputDownPin, d.InputUpPin, d.OutputDownPin, d.OutputUpPin); err != nil {
+	if err := c.s.Update(id, d.Name, d.SunblindType, d.ControlMode, d.InputPin, d.InputDownPin, d.InputUpPin, d.OutputDownPin, d.OutputUpPin, d.PulseDuration, d.DeadTime, d.TravelDownTime, d.TravelUpTime, d.TiltTime, d.OutputPolarity, d.InputSettings); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -149,20 +163,256 @@ func (c *Controller) toggle(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	dir := chi.URLParam(r, "dir")
-	i, err := strconv.Atoi(dir)
+	down, err := parseDirection(chi.URLParam(r, "dir"))
 	if err != nil {
-		log.Printf("Unable to read toggle direction '%s' for sunblind '%d'", dir, id)
-	} else if err := c.s.Toggle(id, i == 0); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err := c.s.Toggle(id, down); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// @Router /api/sunblind/stop/{id} [post]
+// @Param id path int true "path"
+// @Success 200 {string} plain
+// @Security ApiKeyAuth
+func (c *Controller) stop(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.StopMotion(id); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// @Router /api/sunblind/position/{id} [post]
+// @Param id path int true "path"
+// @Param body body positionDto true "body"
+// @Success 200 {string} plain
+// @Accept  json
+// @Produce  plain
+// @Security ApiKeyAuth
+func (c *Controller) position(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var d positionDto
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.MoveTo(id, d.Position); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// @Router /api/sunblind/tilt/{id} [post]
+// @Param id path int true "path"
+// @Param body body tiltDto true "body"
+// @Success 200 {string} plain
+// @Accept  json
+// @Produce  plain
+// @Security ApiKeyAuth
+func (c *Controller) tilt(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var d tiltDto
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.TiltTo(id, d.Tilt); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// @Router /api/sunblind/group/browse [post]
+// @Success 200 {array} dto.SunblindGroup
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+func (c *Controller) browseGroups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+	ret, err := c.s.BrowseGroups()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	retj, _ := json.Marshal(ret)
+	w.WriteHeader(http.StatusOK)
+	w.Write(retj)
+}
+
+// @Router /api/sunblind/group/create [post]
+// @Param body body groupDto true "body"
+// @Success 200 {string} plain
+// @Accept  json
+// @Produce  plain
+// @Security ApiKeyAuth
+func (c *Controller) createGroup(w http.ResponseWriter, r *http.Request) {
+	var d groupDto
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.CreateGroup(d.Name, d.Stagger, d.Members); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// @Router /api/sunblind/group/update/{id} [patch]
+// @Param id path int true "path"
+// @Param body body groupDto true "body"
+// @Success 200 {string} plain
+// @Accept  json
+// @Produce  plain
+// @Security ApiKeyAuth
+func (c *Controller) updateGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var d groupDto
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.UpdateGroup(id, d.Name, d.Stagger, d.Members); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// @Router /api/sunblind/group/delete/{id} [delete]
+// @Param id path int true "path"
+// @Success 200 {string} plain
+// @Security ApiKeyAuth
+func (c *Controller) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.DeleteGroup(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// @Router /api/sunblind/group/move/{id}/{dir} [post]
+// @Param id path int true "path"
+// @Param dir path string true "path"
+// @Success 200 {string} plain
+// @Security ApiKeyAuth
+func (c *Controller) moveGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	down, err := parseDirection(chi.URLParam(r, "dir"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.MoveGroup(id, down); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// @Router /api/sunblind/group/stop/{id} [post]
+// @Param id path int true "path"
+// @Success 200 {string} plain
+// @Security ApiKeyAuth
+func (c *Controller) stopGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.StopGroup(id); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// @Router /api/sunblind/group/position/{id} [post]
+// @Param id path int true "path"
+// @Param body body positionDto true "body"
+// @Success 200 {string} plain
+// @Accept  json
+// @Produce  plain
+// @Security ApiKeyAuth
+func (c *Controller) positionGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var d positionDto
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.s.PositionGroup(id, d.Position); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// parseDirection reads the direction of a toggle, 0 is down and 1 is up.
+func parseDirection(dir string) (bool, error) {
+	switch dir {
+	case "0":
+		return true, nil
+	case "1":
+		return false, nil
+	default:
+		return false, fmt.Errorf("Invalid direction '%s'", dir)
+	}
+}
+
+type positionDto struct {
+	// percent closed
+	Position uint8 `json:"position"`
+}
+
+type tiltDto struct {
+	// percent closed, the slats are open at 0
+	Tilt uint8 `json:"tilt"`
+}
+
+type groupDto struct {
+	Name string `json:"name"`
+	// milliseconds between the members starting to move, all at once when not set
+	Stagger domain.Milliseconds `json:"stagger"`
+	// the members in the order they are moved
+	Members []int64 `json:"members"`
 }
 
 type saveDto struct {
-	Name          string     `json:"name"`
-	InputDownPin  domain.Pin `json:"inputdownpin"`
-	InputUpPin    domain.Pin `json:"inputuppin"`
-	OutputDownPin domain.Pin `json:"outputdownpin"`
-	OutputUpPin   domain.Pin `json:"outputuppin"`
+	Name           string              `json:"name"`
+	SunblindType   enum.SunblindType   `json:"sunblindtype"`
+	ControlMode    enum.ControlMode    `json:"controlmode"`
+	InputPin       domain.Pin          `json:"inputpin" swaggertype:"string"`
+	InputDownPin   domain.Pin          `json:"inputdownpin" swaggertype:"string"`
+	InputUpPin     domain.Pin          `json:"inputuppin" swaggertype:"string"`
+	OutputDownPin  domain.Pin          `json:"outputdownpin" swaggertype:"string"`
+	OutputUpPin    domain.Pin          `json:"outputuppin" swaggertype:"string"`
+	PulseDuration  domain.Milliseconds `json:"pulseduration"`
+	DeadTime       domain.Milliseconds `json:"deadtime"`
+	TravelDownTime domain.Milliseconds `json:"traveldowntime"`
+	TravelUpTime   domain.Milliseconds `json:"traveluptime"`
+	TiltTime       domain.Milliseconds `json:"tilttime"`
+	OutputPolarity enum.Polarity       `json:"outputpolarity"`
+	domain.InputSettings
 }
@@ -0,0 +1,47 @@
+package sunblind
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
+)
+
+func TestGroupManagementRequiresAdmin(t *testing.T) {
+	auth := jwtauth.New("HS256", []byte("secret"), nil)
+	r := chi.NewRouter()
+	r.Use(jwtauth.Verifier(auth))
+	r.Route("/sunblind", CreateController(nil).Route)
+
+	tests := []struct {
+		method string
+		url    string
+		role   domain.Role
+		status int
+	}{
+		{http.MethodPost, "/sunblind/group/create", domain.RoleUser, http.StatusForbidden},
+		{http.MethodPatch, "/sunblind/group/update/1", domain.RoleUser, http.StatusForbidden},
+		{http.MethodDelete, "/sunblind/group/delete/1", domain.RoleGuest, http.StatusForbidden},
+		// an admin gets through to the handler, which refuses the malformed request
+		{http.MethodPost, "/sunblind/group/create", domain.RoleAdmin, http.StatusBadRequest},
+		{http.MethodPatch, "/sunblind/group/update/x", domain.RoleAdmin, http.StatusBadRequest},
+		{http.MethodDelete, "/sunblind/group/delete/x", domain.RoleAdmin, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		_, token, err := auth.Encode(jwt.MapClaims{"uid": 1, "pwd": "hash", "role": int(test.role)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(test.method, test.url, nil)
+		req.Header.Set("Authorization", "BEARER "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("%s %s as %v: expected status %d, got %d", test.method, test.url, test.role, test.status, w.Code)
+		}
+	}
+}
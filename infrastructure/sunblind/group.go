@@ -0,0 +1,292 @@
+package sunblind
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/dto"
+	"github.com/Erexo/Ventana/core/entity"
+	"github.com/Erexo/Ventana/core/utils"
+	"github.com/Erexo/Ventana/infrastructure/db"
+	"github.com/georgysavva/scany/sqlscan"
+)
+
+// groupMove is a group command still being passed to the members one after another.
+type groupMove struct {
+	cancel context.CancelFunc
+}
+
+func (s *Service) BrowseGroups() ([]*dto.SunblindGroup, error) {
+	var groups []*dto.SunblindGroup
+	if err := db.Select(&groups, "SELECT id, name, stagger FROM sunblindgroup ORDER BY id ASC"); err != nil {
+		return nil, err
+	}
+	var members []memberData
+	if err := db.Select(&members, "SELECT groupid, sunblindid FROM sunblindgroupmember ORDER BY id ASC"); err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		group.Members = filterMembers(members, group.Id)
+	}
+	return groups, nil
+}
+
+func (s *Service) CreateGroup(name string, stagger domain.Milliseconds, members []int64) error {
+	if err := validateGroup(&name, stagger, members); err != nil {
+		return err
+	}
+
+	tx, close, err := db.GetTransaction()
+	if err != nil {
+		return err
+	}
+	defer close()
+	r, err := tx.Exec("INSERT INTO sunblindgroup (name, stagger) VALUES (?, ?)", name, stagger)
+	if err != nil {
+		return err
+	}
+	id, _ := r.LastInsertId()
+	if err := saveMembers(tx, id, members); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Created sunblind group '%d' with Name %s\n", id, name)
+	return nil
+}
+
+func (s *Service) UpdateGroup(id int64, name string, stagger domain.Milliseconds, members []int64) error {
+	if err := validateGroup(&name, stagger, members); err != nil {
+		return err
+	}
+
+	tx, close, err := db.GetTransaction()
+	if err != nil {
+		return err
+	}
+	defer close()
+	r, err := tx.Exec("UPDATE sunblindgroup SET name=?, stagger=? WHERE id=?", name, stagger, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := r.RowsAffected(); n == 0 {
+		return fmt.Errorf("Sunblind group '%d' does not exist", id)
+	}
+	if _, err := tx.Exec("DELETE FROM sunblindgroupmember WHERE groupid=?", id); err != nil {
+		return err
+	}
+	if err := saveMembers(tx, id, members); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Updated sunblind group '%d'\n", id)
+	return nil
+}
+
+func (s *Service) DeleteGroup(id int64) error {
+	r, err := db.Exec("DELETE FROM sunblindgroup WHERE id=?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := r.RowsAffected(); n == 0 {
+		return fmt.Errorf("Sunblind group '%d' does not exist", id)
+	}
+	s.cancelGroupMove(id)
+
+	log.Printf("Deleted sunblind group '%d'\n", id)
+	return nil
+}
+
+// MoveGroup moves the members of the group all the way down or up.
+func (s *Service) MoveGroup(id int64, down bool) error {
+	return s.fanOut(id, func(sb loadData) error {
+		return s.travelToEnd(sb, down)
+	})
+}
+
+// PositionGroup moves the members of the group to the position, in percent closed.
+func (s *Service) PositionGroup(id int64, position uint8) error {
+	if position > 100 {
+		return errors.New("Position must be between 0 and 100")
+	}
+	return s.fanOut(id, func(sb loadData) error {
+		return s.MoveTo(sb.Id, position)
+	})
+}
+
+// StopGroup stops the members of the group all at once, along with a command still being passed to them.
+func (s *Service) StopGroup(id int64) error {
+	group, err := getGroup(id)
+	if err != nil {
+		return err
+	}
+	s.cancelGroupMove(id)
+	var ret error
+	for _, member := range group.Members {
+		ret = utils.ConcatErrors(ret, s.StopMotion(member))
+	}
+	return ret
+}
+
+// fanOut passes the command to the members of the group in their order. With the stagger set the motors are
+// started one after another, so they do not draw their inrush current at once, and the command ends in the background.
+// A later command to the group cancels the members not reached yet.
+func (s *Service) fanOut(id int64, command func(sb loadData) error) error {
+	group, err := getGroup(id)
+	if err != nil {
+		return err
+	}
+	if len(group.Members) == 0 {
+		return fmt.Errorf("Sunblind group '%d' has no members", id)
+	}
+	sunblinds := make([]loadData, 0, len(group.Members))
+	for _, member := range group.Members {
+		sb, err := getData(member)
+		if err != nil {
+			return err
+		}
+		sunblinds = append(sunblinds, sb)
+	}
+
+	if group.Stagger <= 0 {
+		s.cancelGroupMove(id)
+		var ret error
+		for _, sb := range sunblinds {
+			ret = utils.ConcatErrors(ret, command(sb))
+		}
+		return ret
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	move := &groupMove{cancel: cancel}
+	s.mux.Lock()
+	if previous, ok := s.moves[id]; ok {
+		previous.cancel()
+	}
+	s.moves[id] = move
+	s.movesDone.Add(1)
+	s.mux.Unlock()
+	go func() {
+		defer s.movesDone.Done()
+		defer s.endGroupMove(id, move)
+		for i, sb := range sunblinds {
+			if i > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(group.Stagger.Duration()):
+				}
+			}
+			if err := command(sb); err != nil {
+				log.Printf("Sunblind group '%d' command failed for sunblind '%d': %v\n", id, sb.Id, err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *Service) cancelGroupMove(id int64) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if move, ok := s.moves[id]; ok {
+		move.cancel()
+		delete(s.moves, id)
+	}
+}
+
+func (s *Service) endGroupMove(id int64, move *groupMove) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	move.cancel()
+	if s.moves[id] == move {
+		delete(s.moves, id)
+	}
+}
+
+// cancelGroupMoves stops passing the group commands, the members not reached yet are left as they are.
+func (s *Service) cancelGroupMoves() {
+	s.mux.Lock()
+	for id, move := range s.moves {
+		move.cancel()
+		delete(s.moves, id)
+	}
+	s.mux.Unlock()
+	s.movesDone.Wait()
+}
+
+func getGroup(id int64) (dto.SunblindGroup, error) {
+	var group dto.SunblindGroup
+	if err := db.Get(&group, "SELECT id, name, stagger FROM sunblindgroup WHERE id=?", id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dto.SunblindGroup{}, fmt.Errorf("Sunblind group '%d' does not exist", id)
+		}
+		return dto.SunblindGroup{}, err
+	}
+	if err := db.Select(&group.Members, "SELECT sunblindid FROM sunblindgroupmember WHERE groupid=? ORDER BY id ASC", id); err != nil {
+		return dto.SunblindGroup{}, err
+	}
+	return group, nil
+}
+
+// saveMembers adds the members in their order, which is the order the group commands reach them.
+func saveMembers(tx *sql.Tx, id int64, members []int64) error {
+	var sunblinds []int64
+	if err := sqlscan.Select(db.Ctx(), tx, &sunblinds, "SELECT id FROM sunblind"); db.IsError(err) {
+		return err
+	}
+	exists := make(map[int64]bool, len(sunblinds))
+	for _, sunblindId := range sunblinds {
+		exists[sunblindId] = true
+	}
+	for _, member := range members {
+		if !exists[member] {
+			return fmt.Errorf("Sunblind '%d' does not exist", member)
+		}
+		if _, err := tx.Exec("INSERT INTO sunblindgroupmember (groupid, sunblindid) VALUES (?, ?)", id, member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateGroup(name *string, stagger domain.Milliseconds, members []int64) error {
+	if err := entity.ValidateName(name); err != nil {
+		return fmt.Errorf("Name: %w", err)
+	}
+	if stagger < 0 {
+		return errors.New("Stagger must not be negative")
+	}
+	listed := make(map[int64]bool, len(members))
+	for _, member := range members {
+		if listed[member] {
+			return fmt.Errorf("Sunblind '%d' is listed more than once", member)
+		}
+		listed[member] = true
+	}
+	return nil
+}
+
+type memberData struct {
+	GroupId    int64 `db:"groupid"`
+	SunblindId int64 `db:"sunblindid"`
+}
+
+func filterMembers(members []memberData, id int64) []int64 {
+	ret := []int64{}
+	for _, member := range members {
+		if member.GroupId == id {
+			ret = append(ret, member.SunblindId)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,326 @@
+package sunblind
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Erexo/Ventana/core/domain"
+	"github.com/Erexo/Ventana/core/enum"
+	"github.com/Erexo/Ventana/infrastructure/db"
+)
+
+// a position this close to the target is not worth starting the motor for
+const positionTolerance = 0.5
+
+// travel follows the motor of a sunblind and estimates its position, in percent closed, from the time it runs either way.
+// The slats of a venetian blind turn first, their tilt is estimated in percent closed as well.
+type travel struct {
+	// full travel times, the position is not estimated without them
+	downTime time.Duration
+	upTime   time.Duration
+	// time the slats take to turn all the way, zero for a blind without them
+	tiltTime time.Duration
+
+	// state at the time the motor started, or the current one with the motor stopped
+	position float64
+	tilt     float64
+	since    time.Time
+	down     bool
+	// nextDown is the way a press starts the motor, the other way than it last moved, up at first
+	nextDown bool
+}
+
+func (t *travel) isTracked() bool {
+	return t.downTime > 0 && t.upTime > 0
+}
+
+func (t *travel) isMoving() bool {
+	return !t.since.IsZero()
+}
+
+func (t *travel) motion() enum.Motion {
+	switch {
+	case !t.isMoving():
+		return enum.MotionIdle
+	case t.down:
+		return enum.MotionDown
+	default:
+		return enum.MotionUp
+	}
+}
+
+func (t *travel) canTilt() bool {
+	return t.tiltTime > 0
+}
+
+// stateAt tells the position and the tilt, the slats turn before the blind starts to travel.
+func (t *travel) stateAt(now time.Time) (float64, float64) {
+	if !t.isMoving() {
+		return t.position, t.tilt
+	}
+	elapsed := now.Sub(t.since)
+	position, tilt := t.position, t.tilt
+	if t.canTilt() {
+		turn := t.turnTime(tilt, t.down)
+		if elapsed < turn {
+			change := 100 * float64(elapsed) / float64(t.tiltTime)
+			if t.down {
+				return position, tilt + change
+			}
+			return position, tilt - change
+		}
+		elapsed -= turn
+		tilt = 0
+		if t.down {
+			tilt = 100
+		}
+	}
+	if !t.isTracked() {
+		return position, tilt
+	}
+	if t.down {
+		return math.Min(100, position+100*float64(elapsed)/float64(t.downTime)), tilt
+	}
+	return math.Max(0, position-100*float64(elapsed)/float64(t.upTime)), tilt
+}
+
+// turnTime is the time the slats take to turn from the tilt all the way the direction.
+func (t *travel) turnTime(tilt float64, down bool) time.Duration {
+	if down {
+		tilt = 100 - tilt
+	}
+	return time.Duration(tilt / 100 * float64(t.tiltTime))
+}
+
+func (t *travel) start(down bool, now time.Time) {
+	t.position, t.tilt = t.stateAt(now)
+	t.since, t.down, t.nextDown = now, down, !down
+}
+
+func (t *travel) stop(now time.Time) {
+	t.position, t.tilt = t.stateAt(now)
+	t.since = time.Time{}
+}
+
+// drive tells the direction and the time to run the motor to reach the position, no time when it is there already.
+// Either end is overrun, so the motor runs into its limit switch and the estimate is corrected.
+func (t *travel) drive(target float64, now time.Time) (bool, time.Duration) {
+	position, tilt := t.stateAt(now)
+	down := target > position
+	full := t.upTime
+	if down {
+		full = t.downTime
+	}
+	distance := math.Abs(target - position)
+	if target == 0 || target == 100 {
+		return down, time.Duration(distance/100*float64(full)) + t.turnTime(tilt, down) + full/10
+	}
+	if distance < positionTolerance {
+		return down, 0
+	}
+	return down, time.Duration(distance/100*float64(full)) + t.turnTime(tilt, down)
+}
+
+// driveTilt tells the direction and the short time to run the motor to turn the slats to the tilt,
+// which is too short for the blind to travel.
+func (t *travel) driveTilt(target float64, now time.Time) (bool, time.Duration) {
+	_, tilt := t.stateAt(now)
+	down := target > tilt
+	distance := math.Abs(target - tilt)
+	if distance < positionTolerance {
+		return down, 0
+	}
+	return down, time.Duration(distance / 100 * float64(t.tiltTime))
+}
+
+// track estimates the position of the sunblind from now on, restarted tells its motor was stopped along with its pairs.
+func (s *Service) track(sb loadData, restarted bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	t, ok := s.travels[sb.Id]
+	if ok {
+		if restarted && t.isMoving() {
+			t.stop(time.Now())
+		}
+	} else {
+		t = &travel{position: sb.Position, tilt: sb.Tilt}
+		s.travels[sb.Id] = t
+	}
+	t.downTime, t.upTime = sb.TravelDownTime.Duration(), sb.TravelUpTime.Duration()
+	t.tiltTime = 0
+	if sb.SunblindType == enum.SunblindTypeVenetian {
+		t.tiltTime = sb.TiltTime.Duration()
+	}
+}
+
+func (s *Service) untrack(id int64) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.travels, id)
+}
+
+// getState tells the way the motor of the sunblind runs, its rounded position and tilt, nil when they are not estimated.
+func (s *Service) getState(id int64) (enum.Motion, *uint8, *uint8) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	t, ok := s.travels[id]
+	if !ok {
+		return enum.MotionIdle, nil, nil
+	}
+	var position, tilt *uint8
+	p, a := t.stateAt(time.Now())
+	if t.isTracked() {
+		position = roundPercent(p)
+	}
+	if t.canTilt() {
+		tilt = roundPercent(a)
+	}
+	return t.motion(), position, tilt
+}
+
+func roundPercent(v float64) *uint8 {
+	ret := uint8(math.Round(v))
+	return &ret
+}
+
+// outputChanged follows the motor of the sunblind as its pairs report their outputs, the position and tilt are saved
+// whenever it stops. It is called by the pair workers, which never wait for the database.
+func (s *Service) outputChanged(id int64, down, state bool, at time.Time) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	t, ok := s.travels[id]
+	if !ok {
+		return
+	}
+	if state {
+		t.start(down, at)
+		return
+	}
+	// the other way started already, when the interlock let it on before this one reported its stop
+	if t.isMoving() && t.down == down {
+		t.stop(at)
+		s.saver.save(id, t.position, t.tilt)
+	}
+}
+
+// savePositions keeps the positions and tilts of the sunblinds still moving, as their motors are stopped with the gpio service.
+func (s *Service) savePositions() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	now := time.Now()
+	for id, t := range s.travels {
+		if t.isMoving() {
+			t.stop(now)
+			s.saver.save(id, t.position, t.tilt)
+		}
+	}
+}
+
+type savedPosition struct {
+	position float64
+	tilt     float64
+}
+
+// positionSaver writes the positions and tilts of the stopped sunblinds without holding up the pair workers,
+// a sunblind stopping again before it is saved only has its latest state written.
+type positionSaver struct {
+	mux       sync.Mutex
+	positions map[int64]savedPosition
+	// pending wakes the saving goroutine once something is recorded
+	pending chan struct{}
+	stop    chan struct{}
+	// saved is closed once the last pending positions are written after stop
+	saved chan struct{}
+}
+
+func createPositionSaver() *positionSaver {
+	return &positionSaver{
+		positions: make(map[int64]savedPosition),
+		pending:   make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		saved:     make(chan struct{}),
+	}
+}
+
+func (s *positionSaver) save(id int64, position, tilt float64) {
+	s.mux.Lock()
+	s.positions[id] = savedPosition{position, tilt}
+	s.mux.Unlock()
+	select {
+	case s.pending <- struct{}{}:
+	default:
+	}
+}
+
+func (s *positionSaver) run() {
+	defer close(s.saved)
+	for {
+		select {
+		case <-s.pending:
+			s.write()
+		case <-s.stop:
+			s.write()
+			return
+		}
+	}
+}
+
+// write saves the recorded positions, the failed ones are kept pending unless changed since and retried with the next write.
+func (s *positionSaver) write() {
+	s.mux.Lock()
+	positions := s.positions
+	s.positions = make(map[int64]savedPosition)
+	s.mux.Unlock()
+	for id, p := range positions {
+		if _, err := db.Exec("UPDATE sunblind SET position=?, tilt=? WHERE id=?", p.position, p.tilt, id); err != nil {
+			log.Printf("Saving the position of sunblind '%d' failed: %v\n", id, err)
+			continue
+		}
+		delete(positions, id)
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for id, p := range positions {
+		if _, ok := s.positions[id]; !ok {
+			s.positions[id] = p
+		}
+	}
+}
+
+// close writes the pending positions and ends the saving, the stops reported afterwards are not saved.
+func (s *positionSaver) close(ctx context.Context) error {
+	close(s.stop)
+	select {
+	case <-s.saved:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func validateTravelTimes(travelDownTime, travelUpTime domain.Milliseconds) error {
+	if travelDownTime < 0 || travelUpTime < 0 {
+		return errors.New("Travel time must not be negative")
+	}
+	if (travelDownTime == 0) != (travelUpTime == 0) {
+		return errors.New("Travel time must be set for both directions or for neither")
+	}
+	return nil
+}
+
+func validateTilt(sunblindType enum.SunblindType, tiltTime domain.Milliseconds) error {
+	if !sunblindType.IsValid() {
+		return errors.New("Invalid sunblind type")
+	}
+	if tiltTime < 0 {
+		return errors.New("Tilt time must not be negative")
+	}
+	if sunblindType == enum.SunblindTypeVenetian && tiltTime == 0 {
+		return errors.New("Tilt time must be set for a venetian blind")
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package sunblind
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func createTestTravel(tiltTime time.Duration, position, tilt float64) *travel {
+	return &travel{
+		downTime: 20 * time.Second,
+		upTime:   10 * time.Second,
+		tiltTime: tiltTime,
+		position: position,
+		tilt:     tilt,
+	}
+}
+
+func expectState(t *testing.T, what string, tr *travel, at time.Time, position, tilt float64) {
+	t.Helper()
+	p, a := tr.stateAt(at)
+	if math.Abs(p-position) > 1e-6 || math.Abs(a-tilt) > 1e-6 {
+		t.Errorf("%s: expected position %v and tilt %v, got %v and %v", what, position, tilt, p, a)
+	}
+}
+
+func expectDrive(t *testing.T, what string, down bool, d time.Duration, expectedDown bool, expected time.Duration) {
+	t.Helper()
+	if down != expectedDown || d < expected-time.Millisecond || d > expected+time.Millisecond {
+		t.Errorf("%s: expected down %v for %v, got down %v for %v", what, expectedDown, expected, down, d)
+	}
+}
+
+func TestStateAtTravel(t *testing.T) {
+	start := time.Now()
+	tr := createTestTravel(0, 0, 0)
+	expectState(t, "stopped", tr, start.Add(time.Minute), 0, 0)
+
+	tr.start(true, start)
+	expectState(t, "going down", tr, start.Add(5*time.Second), 25, 0)
+	expectState(t, "past the bottom", tr, start.Add(30*time.Second), 100, 0)
+
+	tr = createTestTravel(0, 50, 0)
+	tr.start(false, start)
+	expectState(t, "going up", tr, start.Add(2*time.Second), 30, 0)
+	expectState(t, "past the top", tr, start.Add(10*time.Second), 0, 0)
+}
+
+func TestStateAtTurnsSlatsFirst(t *testing.T) {
+	start := time.Now()
+	tr := createTestTravel(time.Second, 0, 0)
+	tr.start(true, start)
+	expectState(t, "turning down", tr, start.Add(500*time.Millisecond), 0, 50)
+	expectState(t, "going down", tr, start.Add(3*time.Second), 10, 100)
+
+	tr = createTestTravel(time.Second, 50, 100)
+	tr.start(false, start)
+	expectState(t, "turning up", tr, start.Add(250*time.Millisecond), 50, 75)
+	expectState(t, "going up", tr, start.Add(2*time.Second), 40, 0)
+
+	// without the travel times only the tilt is estimated
+	tr = createTestTravel(time.Second, 50, 0)
+	tr.downTime, tr.upTime = 0, 0
+	tr.start(true, start)
+	expectState(t, "untracked", tr, start.Add(5*time.Second), 50, 100)
+}
+
+func TestStartAndStopKeepTheState(t *testing.T) {
+	start := time.Now()
+	tr := createTestTravel(time.Second, 0, 0)
+	tr.start(true, start)
+	tr.stop(start.Add(3 * time.Second))
+	if tr.isMoving() {
+		t.Fatal("Expected the travel to be stopped")
+	}
+	expectState(t, "stopped", tr, start.Add(time.Minute), 10, 100)
+
+	// reversing keeps the state reached so far
+	tr = createTestTravel(time.Second, 0, 0)
+	tr.start(true, start)
+	tr.start(false, start.Add(3*time.Second))
+	if !tr.isMoving() || tr.down {
+		t.Fatal("Expected the travel to go up")
+	}
+	expectState(t, "reversed", tr, start.Add(3*time.Second+500*time.Millisecond), 10, 50)
+}
+
+func TestTurnTime(t *testing.T) {
+	tr := createTestTravel(time.Second, 0, 0)
+	if d := tr.turnTime(25, true); d != 750*time.Millisecond {
+		t.Errorf("Expected 750ms turning down, got %v", d)
+	}
+	if d := tr.turnTime(25, false); d != 250*time.Millisecond {
+		t.Errorf("Expected 250ms turning up, got %v", d)
+	}
+	tr.tiltTime = 0
+	if d := tr.turnTime(25, true); d != 0 {
+		t.Errorf("Expected no turn without slats, got %v", d)
+	}
+}
+
+func TestDrive(t *testing.T) {
+	now := time.Now()
+	tr := createTestTravel(0, 50, 0)
+	down, d := tr.drive(75, now)
+	expectDrive(t, "down", down, d, true, 5*time.Second)
+	down, d = tr.drive(25, now)
+	expectDrive(t, "up", down, d, false, 2500*time.Millisecond)
+	down, d = tr.drive(50.3, now)
+	expectDrive(t, "within tolerance", down, d, true, 0)
+	// the ends are overrun by a tenth of the full travel
+	down, d = tr.drive(100, now)
+	expectDrive(t, "bottom", down, d, true, 12*time.Second)
+	down, d = tr.drive(0, now)
+	expectDrive(t, "top", down, d, false, 6*time.Second)
+
+	tr = createTestTravel(0, 0, 0)
+	down, d = tr.drive(0, now)
+	expectDrive(t, "top again", down, d, false, time.Second)
+
+	tr = createTestTravel(time.Second, 50, 0)
+	down, d = tr.drive(75, now)
+	expectDrive(t, "down turning", down, d, true, 6*time.Second)
+	down, d = tr.drive(25, now)
+	expectDrive(t, "up turned", down, d, false, 2500*time.Millisecond)
+}
+
+func TestDriveTilt(t *testing.T) {
+	now := time.Now()
+	tr := createTestTravel(time.Second, 50, 0)
+	down, d := tr.driveTilt(40, now)
+	expectDrive(t, "down", down, d, true, 400*time.Millisecond)
+
+	tr = createTestTravel(time.Second, 50, 40)
+	down, d = tr.driveTilt(10, now)
+	expectDrive(t, "up", down, d, false, 300*time.Millisecond)
+	down, d = tr.driveTilt(40.2, now)
+	expectDrive(t, "within tolerance", down, d, true, 0)
+}
+
+func TestOutputChangedSavesStops(t *testing.T) {
+	s := &Service{travels: map[int64]*travel{1: createTestTravel(0, 0, 0)}, saver: createPositionSaver()}
+	start := time.Now()
+	s.outputChanged(1, true, true, start)
+	s.outputChanged(1, true, false, start.Add(5*time.Second))
+	if p, ok := s.saver.positions[1]; !ok || math.Abs(p.position-25) > 1e-6 {
+		t.Fatalf("Expected position 25 to be saved, got %v", s.saver.positions)
+	}
+
+	// the up output came on before the down one reported its stop
+	delete(s.saver.positions, 1)
+	s.outputChanged(1, true, true, start.Add(6*time.Second))
+	s.outputChanged(1, false, true, start.Add(8*time.Second))
+	s.outputChanged(1, true, false, start.Add(8*time.Second))
+	if !s.travels[1].isMoving() || s.travels[1].down {
+		t.Fatal("Expected the sunblind to keep going up")
+	}
+	if len(s.saver.positions) != 0 {
+		t.Fatalf("Expected nothing saved while moving, got %v", s.saver.positions)
+	}
+	s.outputChanged(1, false, false, start.Add(9*time.Second))
+	if p := s.saver.positions[1]; math.Abs(p.position-25) > 1e-6 {
+		t.Fatalf("Expected position 25 to be saved, got %v", p.position)
+	}
+
+	// an unknown sunblind is ignored
+	s.outputChanged(2, true, true, start)
+}
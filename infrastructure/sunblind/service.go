@@ -1,27 +1,44 @@
 package sunblind
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/Erexo/Ventana/core/domain"
 	"github.com/Erexo/Ventana/core/dto"
 	"github.com/Erexo/Ventana/core/entity"
 	"github.com/Erexo/Ventana/core/enum"
+	"github.com/Erexo/Ventana/core/utils"
 	"github.com/Erexo/Ventana/infrastructure/db"
 	"github.com/Erexo/Ventana/infrastructure/gpio"
+	"github.com/Erexo/Ventana/infrastructure/inventory"
 	"github.com/georgysavva/scany/sqlscan"
 )
 
 type Service struct {
 	gs *gpio.Service
+	is *inventory.Service
+
+	mux     sync.Mutex
+	travels map[int64]*travel
+	// saver is started by Start, the positions are saved through it whenever a motor stops
+	saver *positionSaver
+	// moves are the staggered group commands in progress, by the group
+	moves     map[int64]*groupMove
+	movesDone sync.WaitGroup
 }
 
-func CreateService(pm *gpio.Service) *Service {
+func CreateService(pm *gpio.Service, is *inventory.Service) *Service {
 	return &Service{
-		gs: pm,
+		gs:      pm,
+		is:      is,
+		travels: make(map[int64]*travel),
+		moves:   make(map[int64]*groupMove),
 	}
 }
 
@@ -71,10 +88,14 @@ func (s *Service) SaveOrder(userId int64, order []int64) error {
 
 func (s *Service) Browse(userId int64) ([]*dto.Sunblind, error) {
 	var sunblinds []*dto.Sunblind
-	err := db.Select(&sunblinds, "SELECT id, name, inputdownpin, inputuppin, outputdownpin, outputuppin FROM sunblind ORDER BY id ASC")
+	err := db.Select(&sunblinds, "SELECT id, name, sunblindtype, controlmode, inputpin, inputdownpin, inputuppin, outputdownpin, outputuppin, pulseduration, deadtime, traveldowntime, traveluptime, tilttime, outputpolarity, inputpolarity, inputpull, debounce, pressaction, longpressaction, doublepressaction, releaseaction FROM sunblind ORDER BY id ASC")
 	if err != nil {
 		return nil, err
 	}
+	for _, sunblind := range sunblinds {
+		sunblind.Degraded = s.gs.IsDegraded(sunblind.InputPin, sunblind.InputDownPin, sunblind.InputUpPin, sunblind.OutputDownPin, sunblind.OutputUpPin)
+		sunblind.Motion, sunblind.Position, sunblind.Tilt = s.getState(sunblind.Id)
+	}
 	var order []int64
 	if err := db.Select(&order, "SELECT sunblindid FROM sunblindorder WHERE userid=? ORDER BY id ASC", userId); err != nil {
 		return nil, err
@@ -105,24 +126,71 @@ func (s *Service) Browse(userId int64) ([]*dto.Sunblind, error) {
 	return ret, nil
 }
 
-func (s *Service) Create(name string, inputDownPin, inputUpPin, outputDownPin, outputUpPin domain.Pin) error {
+func (s *Service) Create(name string, sunblindType enum.SunblindType, controlMode enum.ControlMode, inputPin, inputDownPin, inputUpPin, outputDownPin, outputUpPin domain.Pin, pulseDuration, deadTime, travelDownTime, travelUpTime, tiltTime domain.Milliseconds, outputPolarity enum.Polarity, settings domain.InputSettings) error {
 	if err := entity.ValidateName(&name); err != nil {
 		return fmt.Errorf("Name: %w", err)
 	}
-	if err := s.gs.IsPinRegistered(inputDownPin, inputUpPin, outputDownPin, outputUpPin); err != nil {
+	if err := validatePulseDuration(pulseDuration); err != nil {
+		return err
+	}
+	if deadTime < 0 {
+		return errors.New("Dead time must not be negative")
+	}
+	if err := validateTravelTimes(travelDownTime, travelUpTime); err != nil {
+		return err
+	}
+	if err := validateTilt(sunblindType, tiltTime); err != nil {
+		return err
+	}
+	if !outputPolarity.IsValid() {
+		return errors.New("Invalid output polarity")
+	}
+	if err := validateInputs(controlMode, inputPin, outputDownPin, outputUpPin); err != nil {
+		return err
+	}
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	if err := s.gs.IsPinRegistered(inputPin, inputDownPin, inputUpPin, outputDownPin, outputUpPin); err != nil {
 		return err
 	}
 
-	r, err := db.Exec("INSERT INTO sunblind (name, inputdownpin, inputuppin, outputdownpin, outputuppin) VALUES (?, ?, ?, ?, ?)", name, inputDownPin, inputUpPin, outputDownPin, outputUpPin)
+	tx, close, err := db.GetTransaction()
+	if err != nil {
+		return err
+	}
+	defer close()
+	r, err := tx.Exec("INSERT INTO sunblind (name, sunblindtype, controlmode, inputpin, inputdownpin, inputuppin, outputdownpin, outputuppin, pulseduration, deadtime, traveldowntime, traveluptime, tilttime, outputpolarity, inputpolarity, inputpull, debounce, pressaction, longpressaction, doublepressaction, releaseaction) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		name, sunblindType, controlMode, inputPin, inputDownPin, inputUpPin, outputDownPin, outputUpPin, pulseDuration, deadTime, travelDownTime, travelUpTime, tiltTime, outputPolarity, settings.InputPolarity, settings.InputPull, settings.Debounce, settings.PressAction, settings.LongPressAction, settings.DoublePressAction, settings.ReleaseAction)
 	if err != nil {
 		return err
 	}
 	id, _ := r.LastInsertId()
 
-	if err := s.gs.RegisterPinPair(inputDownPin, outputDownPin, enum.PairTypeTimed); err != nil {
+	sunblind := loadData{
+		Id:             id,
+		SunblindType:   sunblindType,
+		ControlMode:    controlMode,
+		InputPin:       inputPin,
+		InputDownPin:   inputDownPin,
+		InputUpPin:     inputUpPin,
+		OutputDownPin:  outputDownPin,
+		OutputUpPin:    outputUpPin,
+		PulseDuration:  pulseDuration,
+		DeadTime:       deadTime,
+		TravelDownTime: travelDownTime,
+		TravelUpTime:   travelUpTime,
+		TiltTime:       tiltTime,
+		OutputPolarity: outputPolarity,
+		InputSettings:  settings,
+	}
+	if err := s.is.Claim(tx, enum.DeviceSunblind, id, name, getClaims(sunblind)...); err != nil {
 		return err
 	}
-	if err := s.gs.RegisterPinPair(inputUpPin, outputUpPin, enum.PairTypeTimed); err != nil {
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if err := s.register(sunblind); err != nil {
 		return err
 	}
 
@@ -130,17 +198,38 @@ func (s *Service) Create(name string, inputDownPin, inputUpPin, outputDownPin, o
 	return nil
 }
 
-func (s *Service) Update(id int64, name string, inputDownPin, inputUpPin, outputDownPin, outputUpPin domain.Pin) error {
+func (s *Service) Update(id int64, name string, sunblindType enum.SunblindType, controlMode enum.ControlMode, inputPin, inputDownPin, inputUpPin, outputDownPin, outputUpPin domain.Pin, pulseDuration, deadTime, travelDownTime, travelUpTime, tiltTime domain.Milliseconds, outputPolarity enum.Polarity, settings domain.InputSettings) error {
 	if err := entity.ValidateName(&name); err != nil {
 		return fmt.Errorf("Name: %w", err)
 	}
+	if err := validatePulseDuration(pulseDuration); err != nil {
+		return err
+	}
+	if deadTime < 0 {
+		return errors.New("Dead time must not be negative")
+	}
+	if err := validateTravelTimes(travelDownTime, travelUpTime); err != nil {
+		return err
+	}
+	if err := validateTilt(sunblindType, tiltTime); err != nil {
+		return err
+	}
+	if !outputPolarity.IsValid() {
+		return errors.New("Invalid output polarity")
+	}
+	if err := validateInputs(controlMode, inputPin, outputDownPin, outputUpPin); err != nil {
+		return err
+	}
+	if err := settings.Validate(); err != nil {
+		return err
+	}
 
 	sunblind, err := getData(id)
 	if err != nil {
 		return err
 	}
 	var newPins []domain.Pin
-	for _, p := range []domain.Pin{inputDownPin, inputUpPin, outputDownPin, outputUpPin} {
+	for _, p := range []domain.Pin{inputPin, inputDownPin, inputUpPin, outputDownPin, outputUpPin} {
 		if !sunblind.ContainsPin(p) {
 			newPins = append(newPins, p)
 		}
@@ -151,12 +240,53 @@ func (s *Service) Update(id int64, name string, inputDownPin, inputUpPin, output
 		}
 	}
 
-	if _, err := db.Exec("UPDATE sunblind SET name=?, inputdownpin=?, inputuppin=?, outputdownpin=?, outputuppin=? WHERE id=?", name, inputDownPin, inputUpPin, outputDownPin, outputUpPin, id); err != nil {
+	updated := loadData{
+		Id:             id,
+		SunblindType:   sunblindType,
+		ControlMode:    controlMode,
+		InputPin:       inputPin,
+		InputDownPin:   inputDownPin,
+		InputUpPin:     inputUpPin,
+		OutputDownPin:  outputDownPin,
+		OutputUpPin:    outputUpPin,
+		PulseDuration:  pulseDuration,
+		DeadTime:       deadTime,
+		TravelDownTime: travelDownTime,
+		TravelUpTime:   travelUpTime,
+		TiltTime:       tiltTime,
+		OutputPolarity: outputPolarity,
+		InputSettings:  settings,
+	}
+	tx, close, err := db.GetTransaction()
+	if err != nil {
+		return err
+	}
+	defer close()
+	if _, err := tx.Exec("UPDATE sunblind SET name=?, sunblindtype=?, controlmode=?, inputpin=?, inputdownpin=?, inputuppin=?, outputdownpin=?, outputuppin=?, pulseduration=?, deadtime=?, traveldowntime=?, traveluptime=?, tilttime=?, outputpolarity=?, inputpolarity=?, inputpull=?, debounce=?, pressaction=?, longpressaction=?, doublepressaction=?, releaseaction=? WHERE id=?",
+		name, sunblindType, controlMode, inputPin, inputDownPin, inputUpPin, outputDownPin, outputUpPin, pulseDuration, deadTime, travelDownTime, travelUpTime, tiltTime, outputPolarity, settings.InputPolarity, settings.InputPull, settings.Debounce, settings.PressAction, settings.LongPressAction, settings.DoublePressAction, settings.ReleaseAction, id); err != nil {
+		return err
+	}
+	if err := s.is.Release(tx, enum.DeviceSunblind, id); err != nil {
+		return err
+	}
+	if err := s.is.Claim(tx, enum.DeviceSunblind, id, name, getClaims(updated)...); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
 		return err
 	}
 
-	changeDown := inputDownPin != sunblind.InputDownPin || outputDownPin != sunblind.OutputDownPin
-	changeUp := inputUpPin != sunblind.InputUpPin || outputUpPin != sunblind.OutputUpPin
+	// the interlock can only be replaced with both pairs unregistered
+	changeInterlock := deadTime != sunblind.DeadTime || outputDownPin != sunblind.OutputDownPin || outputUpPin != sunblind.OutputUpPin
+	changeSettings := changeInterlock || pulseDuration != sunblind.PulseDuration || outputPolarity != sunblind.OutputPolarity || settings != sunblind.InputSettings
+	changeDown := changeSettings || inputDownPin != sunblind.InputDownPin
+	changeUp := changeSettings || inputUpPin != sunblind.InputUpPin
+	changeButton := controlMode != sunblind.ControlMode || inputPin != sunblind.InputPin || settings != sunblind.InputSettings
+	if changeButton {
+		if err := s.unregisterButton(sunblind); err != nil {
+			return err
+		}
+	}
 	if changeDown {
 		if err := s.gs.UnregisterPinPair(sunblind.InputDownPin, sunblind.OutputDownPin); err != nil {
 			return err
@@ -167,16 +297,31 @@ func (s *Service) Update(id int64, name string, inputDownPin, inputUpPin, output
 			return err
 		}
 	}
+	if changeInterlock {
+		if err := s.gs.UnregisterInterlock(sunblind.OutputDownPin, sunblind.OutputUpPin); err != nil {
+			return err
+		}
+		if err := s.gs.RegisterInterlock(updated.DeadTime.Duration(), updated.OutputDownPin, updated.OutputUpPin); err != nil {
+			return err
+		}
+	}
 	if changeDown {
-		if err := s.gs.RegisterPinPair(inputDownPin, outputDownPin, enum.PairTypeTimed); err != nil {
+		if err := s.registerDown(updated); err != nil {
 			return err
 		}
 	}
 	if changeUp {
-		if err := s.gs.RegisterPinPair(inputUpPin, outputUpPin, enum.PairTypeTimed); err != nil {
+		if err := s.registerUp(updated); err != nil {
+			return err
+		}
+	}
+	if changeButton {
+		if err := s.registerButton(updated); err != nil {
 			return err
 		}
 	}
+	// the motor stops with its pairs unregistered
+	s.track(updated, changeDown || changeUp)
 
 	log.Printf("Updated sunblind '%d'\n", id)
 	return nil
@@ -187,56 +332,260 @@ func (s *Service) Delete(id int64) error {
 	if err != nil {
 		return err
 	}
-	if _, err := db.Exec("DELETE FROM sunblind WHERE id=?", id); err != nil {
+	tx, close, err := db.GetTransaction()
+	if err != nil {
+		return err
+	}
+	defer close()
+	if _, err := tx.Exec("DELETE FROM sunblind WHERE id=?", id); err != nil {
+		return err
+	}
+	if err := s.is.Release(tx, enum.DeviceSunblind, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
 		return err
 	}
 
+	if err := s.unregisterButton(sunblind); err != nil {
+		return err
+	}
 	if err := s.gs.UnregisterPinPair(sunblind.InputDownPin, sunblind.OutputDownPin); err != nil {
 		return err
 	}
 	if err := s.gs.UnregisterPinPair(sunblind.InputUpPin, sunblind.OutputUpPin); err != nil {
 		return err
 	}
+	if err := s.gs.UnregisterInterlock(sunblind.OutputDownPin, sunblind.OutputUpPin); err != nil {
+		return err
+	}
+	s.untrack(id)
 
 	log.Printf("Deleted sunblind '%d'\n", id)
 	return nil
 }
 
 func (s *Service) Toggle(id int64, down bool) error {
-	var query string
+	var sb loadData
+	if err := db.Get(&sb, "SELECT inputdownpin, inputuppin, outputdownpin, outputuppin FROM sunblind WHERE id=?", id); err != nil {
+		return err
+	}
 	if down {
-		query = "SELECT inputdownpin FROM sunblind WHERE id=?"
-	} else {
-		query = "SELECT inputuppin FROM sunblind WHERE id=?"
+		return s.gs.TogglePin(sb.downPair())
 	}
-	var pin domain.Pin
-	if err := db.Get(&pin, query, id); err != nil {
+	return s.gs.TogglePin(sb.upPair())
+}
+
+// StopMotion stops the motor of the sunblind whichever way it runs, a held wall switch included.
+func (s *Service) StopMotion(id int64) error {
+	sb, err := getData(id)
+	if err != nil {
 		return err
 	}
-	s.gs.TogglePin(pin)
-	return nil
+	return s.halt(sb)
+}
+
+func (s *Service) halt(sb loadData) error {
+	return utils.ConcatErrors(s.gs.SetPinActive(sb.downPair(), false), s.gs.SetPinActive(sb.upPair(), false))
 }
 
-func (s *Service) Load() error {
+// MoveTo drives the sunblind to the position, in percent closed, for the time its travel takes from the estimated one.
+func (s *Service) MoveTo(id int64, position uint8) error {
+	if position > 100 {
+		return errors.New("Position must be between 0 and 100")
+	}
+	sb, err := getData(id)
+	if err != nil {
+		return err
+	}
+	s.mux.Lock()
+	t, ok := s.travels[id]
+	if !ok || !t.isTracked() {
+		s.mux.Unlock()
+		return fmt.Errorf("Sunblind '%d' has no travel time set", id)
+	}
+	down, duration := t.drive(float64(position), time.Now())
+	s.mux.Unlock()
+
+	if duration <= 0 {
+		return s.halt(sb)
+	}
+	pin := sb.upPair()
+	if down {
+		pin = sb.downPair()
+	}
+	return s.gs.SetPinActiveFor(pin, duration)
+}
+
+// travelToEnd moves the sunblind all the way down or up, past the estimated end so its limit switch stops it,
+// or for the pulse of its pairs when the travel times are not set.
+func (s *Service) travelToEnd(sb loadData, down bool) error {
+	var duration time.Duration
+	s.mux.Lock()
+	if t, ok := s.travels[sb.Id]; ok && t.isTracked() {
+		target := 0.0
+		if down {
+			target = 100
+		}
+		_, duration = t.drive(target, time.Now())
+	}
+	s.mux.Unlock()
+
+	pin := sb.upPair()
+	if down {
+		pin = sb.downPair()
+	}
+	if duration > 0 {
+		return s.gs.SetPinActiveFor(pin, duration)
+	}
+	return s.gs.SetPinActive(pin, true)
+}
+
+// TiltTo turns the slats of a venetian blind to the tilt, in percent closed, with a pulse too short for the blind to travel.
+func (s *Service) TiltTo(id int64, tilt uint8) error {
+	if tilt > 100 {
+		return errors.New("Tilt must be between 0 and 100")
+	}
+	sb, err := getData(id)
+	if err != nil {
+		return err
+	}
+	s.mux.Lock()
+	t, ok := s.travels[id]
+	if !ok || !t.canTilt() {
+		s.mux.Unlock()
+		return fmt.Errorf("Sunblind '%d' has no slats to tilt", id)
+	}
+	down, duration := t.driveTilt(float64(tilt), time.Now())
+	s.mux.Unlock()
+
+	if duration <= 0 {
+		return s.halt(sb)
+	}
+	pin := sb.upPair()
+	if down {
+		pin = sb.downPair()
+	}
+	return s.gs.SetPinActiveFor(pin, duration)
+}
+
+// Start registers the sunblinds with their motors stopped, at the positions saved. A sunblind failing to register
+// is logged and left out, so the others are still controlled.
+func (s *Service) Start(ctx context.Context) error {
 	var sunblinds []*loadData
-	err := db.Select(&sunblinds, "SELECT inputdownpin, inputuppin, outputdownpin, outputuppin FROM sunblind")
+	err := db.Select(&sunblinds, "SELECT id, sunblindtype, controlmode, inputpin, inputdownpin, inputuppin, outputdownpin, outputuppin, pulseduration, deadtime, traveldowntime, traveluptime, tilttime, position, tilt, outputpolarity, inputpolarity, inputpull, debounce, pressaction, longpressaction, doublepressaction, releaseaction FROM sunblind")
 	if err != nil {
 		return err
 	}
+	s.saver = createPositionSaver()
+	go s.saver.run()
 	for _, sb := range sunblinds {
-		if err := s.gs.RegisterPinPair(sb.InputDownPin, sb.OutputDownPin, enum.PairTypeTimed); err != nil {
-			return err
+		if err := s.register(*sb); err != nil {
+			log.Printf("Registering sunblind '%d' failed: %v\n", sb.Id, err)
 		}
-		if err := s.gs.RegisterPinPair(sb.InputUpPin, sb.OutputUpPin, enum.PairTypeTimed); err != nil {
+	}
+	return nil
+}
+
+// Stop cancels the group commands and saves the positions of the sunblinds, those still moving included,
+// the motors are stopped along with the gpio service.
+func (s *Service) Stop(ctx context.Context) error {
+	s.cancelGroupMoves()
+	if s.saver == nil {
+		return nil
+	}
+	s.savePositions()
+	if err := s.saver.close(ctx); err != nil {
+		return fmt.Errorf("Sunblind service did not stop in time: %w", err)
+	}
+	return nil
+}
+
+// getClaims lists the pins of the sunblind for the inventory.
+func getClaims(sb loadData) []inventory.Claim {
+	return []inventory.Claim{
+		{Pin: sb.InputPin, Role: enum.PinRoleInput},
+		{Pin: sb.InputDownPin, Role: enum.PinRoleInput},
+		{Pin: sb.InputUpPin, Role: enum.PinRoleInput},
+		{Pin: sb.OutputDownPin, Role: enum.PinRoleOutput},
+		{Pin: sb.OutputUpPin, Role: enum.PinRoleOutput},
+	}
+}
+
+// register interlocks the outputs, so the motor is never driven both ways at once, and registers both directions
+// with the single button driving them. A sunblind failing to register is left with none of its pins registered.
+func (s *Service) register(sb loadData) error {
+	if err := s.gs.RegisterInterlock(sb.DeadTime.Duration(), sb.OutputDownPin, sb.OutputUpPin); err != nil {
+		return err
+	}
+	err := s.registerDown(sb)
+	if err == nil {
+		if err = s.registerUp(sb); err == nil {
+			if err = s.registerButton(sb); err == nil {
+				s.track(sb, true)
+				return nil
+			}
+			err = utils.ConcatErrors(err, s.gs.UnregisterPinPair(sb.InputUpPin, sb.OutputUpPin))
+		}
+		err = utils.ConcatErrors(err, s.gs.UnregisterPinPair(sb.InputDownPin, sb.OutputDownPin))
+	}
+	return utils.ConcatErrors(err, s.gs.UnregisterInterlock(sb.OutputDownPin, sb.OutputUpPin))
+}
+
+func (s *Service) registerDown(sb loadData) error {
+	return s.gs.RegisterPinPair(sb.InputDownPin, sb.OutputDownPin, enum.PairTypeTimed, s.pairOptions(sb.downPair(), true, sb))
+}
+
+func (s *Service) registerUp(sb loadData) error {
+	return s.gs.RegisterPinPair(sb.InputUpPin, sb.OutputUpPin, enum.PairTypeTimed, s.pairOptions(sb.upPair(), false, sb))
+}
+
+// pairOptions applies the actions to the direction of the input, the whole-house actions move every sunblind of the house that way.
+func (s *Service) pairOptions(pairPin domain.Pin, down bool, sb loadData) gpio.PairOptions {
+	opts := gpio.CreatePairOptions(sb.PulseDuration.Duration(), sb.InputSettings, func(action enum.Action) {
+		if err := s.perform(pairPin, down, action); err != nil {
+			log.Printf("Sunblind action %d on pin %v failed: %v\n", action, pairPin, err)
+		}
+	})
+	opts.OutputPolarity = sb.OutputPolarity
+	opts.Owner = fmt.Sprintf("Sunblind '%d' up", sb.Id)
+	if down {
+		opts.Owner = fmt.Sprintf("Sunblind '%d' down", sb.Id)
+	}
+	opts.OnOutput = func(state bool, at time.Time) {
+		s.outputChanged(sb.Id, down, state, at)
+	}
+	return opts
+}
+
+func (s *Service) perform(pairPin domain.Pin, down bool, action enum.Action) error {
+	switch action {
+	case enum.ActionToggle, enum.ActionOn:
+		return s.gs.SetPinActive(pairPin, true)
+	case enum.ActionOff:
+		return s.gs.SetPinActive(pairPin, false)
+	case enum.ActionHouseOn, enum.ActionHouseOff:
+		var sunblinds []loadData
+		if err := db.Select(&sunblinds, "SELECT inputdownpin, inputuppin, outputdownpin, outputuppin FROM sunblind"); err != nil {
 			return err
 		}
+		var ret error
+		for _, sb := range sunblinds {
+			pin := sb.upPair()
+			if down {
+				pin = sb.downPair()
+			}
+			ret = utils.ConcatErrors(ret, s.gs.SetPinActive(pin, action == enum.ActionHouseOn))
+		}
+		return ret
+	default:
+		return nil
 	}
-	return nil
 }
 
 func getData(id int64) (loadData, error) {
 	var sunblind loadData
-	if err := db.Get(&sunblind, "SELECT inputdownpin, inputuppin, outputdownpin, outputuppin FROM sunblind WHERE id=?", id); err != nil {
+	if err := db.Get(&sunblind, "SELECT id, sunblindtype, controlmode, inputpin, inputdownpin, inputuppin, outputdownpin, outputuppin, pulseduration, deadtime, traveldowntime, traveluptime, tilttime, position, tilt, outputpolarity, inputpolarity, inputpull, debounce, pressaction, longpressaction, doublepressaction, releaseaction FROM sunblind WHERE id=?", id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return loadData{}, fmt.Errorf("Sunblind '%d' does not exist", id)
 		}
@@ -246,15 +595,68 @@ func getData(id int64) (loadData, error) {
 }
 
 type loadData struct {
-	InputDownPin  domain.Pin `db:"inputdownpin"`
-	InputUpPin    domain.Pin `db:"inputuppin"`
-	OutputDownPin domain.Pin `db:"outputdownpin"`
-	OutputUpPin   domain.Pin `db:"outputuppin"`
+	Id             int64               `db:"id"`
+	SunblindType   enum.SunblindType   `db:"sunblindtype"`
+	ControlMode    enum.ControlMode    `db:"controlmode"`
+	InputPin       domain.Pin          `db:"inputpin"`
+	InputDownPin   domain.Pin          `db:"inputdownpin"`
+	InputUpPin     domain.Pin          `db:"inputuppin"`
+	OutputDownPin  domain.Pin          `db:"outputdownpin"`
+	OutputUpPin    domain.Pin          `db:"outputuppin"`
+	PulseDuration  domain.Milliseconds `db:"pulseduration"`
+	DeadTime       domain.Milliseconds `db:"deadtime"`
+	TravelDownTime domain.Milliseconds `db:"traveldowntime"`
+	TravelUpTime   domain.Milliseconds `db:"traveluptime"`
+	TiltTime       domain.Milliseconds `db:"tilttime"`
+	Position       float64             `db:"position"`
+	Tilt           float64             `db:"tilt"`
+	OutputPolarity enum.Polarity       `db:"outputpolarity"`
+	domain.InputSettings
+}
+
+func validatePulseDuration(pulseDuration domain.Milliseconds) error {
+	if pulseDuration <= 0 {
+		return errors.New("Pulse duration must be positive")
+	}
+	return nil
+}
+
+// validateInputs checks the wiring of the buttons, a single button is set in place of the optional ones of the directions.
+func validateInputs(controlMode enum.ControlMode, inputPin, outputDownPin, outputUpPin domain.Pin) error {
+	if !controlMode.IsValid() {
+		return errors.New("Invalid control mode")
+	}
+	if controlMode == enum.ControlModeSingle && !inputPin.IsSet() {
+		return errors.New("Single button sunblind requires an input pin")
+	}
+	if controlMode != enum.ControlModeSingle && inputPin.IsSet() {
+		return errors.New("Input pin is used by a single button sunblind only")
+	}
+	if !outputDownPin.IsSet() || !outputUpPin.IsSet() {
+		return errors.New("Sunblind requires both output pins")
+	}
+	return nil
+}
+
+// downPair and upPair address the pairs of the directions, by their outputs when the inputs are not set.
+func (l loadData) downPair() domain.Pin {
+	if l.InputDownPin.IsSet() {
+		return l.InputDownPin
+	}
+	return l.OutputDownPin
+}
+
+func (l loadData) upPair() domain.Pin {
+	if l.InputUpPin.IsSet() {
+		return l.InputUpPin
+	}
+	return l.OutputUpPin
 }
 
 func (l loadData) ContainsPin(pin domain.Pin) bool {
 	switch pin {
-	case l.InputDownPin,
+	case l.InputPin,
+		l.InputDownPin,
 		l.InputUpPin,
 		l.OutputDownPin,
 		l.OutputUpPin:
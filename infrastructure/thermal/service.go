@@ -1,6 +1,7 @@
 package thermal
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -23,6 +24,8 @@ const blockSize = 100
 type Service struct {
 	thermometers    map[int64]*ThermalBlock
 	thermometersMux sync.Mutex
+	cancel          context.CancelFunc
+	done            chan struct{}
 }
 
 func CreateService() *Service {
@@ -173,17 +176,45 @@ func (s *Service) Delete(id int64) error {
 	return nil
 }
 
-func (s *Service) Load() error {
+// Start reads the sensors every update interval, until the service is stopped.
+func (s *Service) Start(ctx context.Context) error {
+	if s.cancel != nil {
+		return errors.New("Thermal service is already started")
+	}
+	// the worker outlives the start, ctx only bounds the start itself
+	workerCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
 	go func() {
-		updateInterval := config.GetConfig().ThermalUpdateInterval
+		defer close(s.done)
+		ticker := time.NewTicker(time.Duration(config.GetConfig().ThermalUpdateInterval) * time.Millisecond)
+		defer ticker.Stop()
 		for {
-			time.Sleep(time.Duration(updateInterval) * time.Millisecond)
-			s.updateSensors()
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+				s.updateSensors()
+			}
 		}
 	}()
 	return nil
 }
 
+// Stop waits for a sensor update in progress to finish.
+func (s *Service) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("Thermal service did not stop in time: %w", ctx.Err())
+	}
+}
+
 func (s *Service) updateSensors() {
 	now := time.Now()
 	defer log.Println("Updated sensors in", time.Now().Sub(now))
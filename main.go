@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"runtime"
 	"runtime/debug"
+	"syscall"
 
 	"github.com/Erexo/Ventana/infrastructure"
 	"github.com/Erexo/Ventana/infrastructure/db"
@@ -28,13 +30,12 @@ func main() {
 		log.Fatal(err)
 	}
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c)
-	go terminate(c)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 
-	infrastructure.Run()
-
-	fmt.Fscanln(os.Stdout)
+	if err := infrastructure.Run(ctx); err != nil {
+		log.Println("Terminated with error:", err)
+	}
 	fmt.Println("Bye")
 }
 
@@ -51,12 +52,3 @@ func panic() {
 		fmt.Println(stack)
 	}
 }
-
-func terminate(c chan os.Signal) {
-	x := <-c
-	log.Println("Terminated:", x)
-	if err := infrastructure.Terminate(); err != nil {
-		log.Println("Close failed:", err)
-	}
-	os.Exit(0)
-}